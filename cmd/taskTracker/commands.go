@@ -0,0 +1,1031 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/query"
+	"taskTracker/pkg/recur"
+	"taskTracker/pkg/task"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// runFunc runs a command with its positional arguments.
+type runFunc func(a *app, args []string) error
+
+type command struct {
+	name    string
+	args    string // synopsis of the positional arguments
+	summary string
+	// setup registers the flags of the command on fs and returns the function running it
+	setup func(fs *flag.FlagSet) runFunc
+}
+
+var commands = []command{
+	{name: "add", args: "[description]", summary: "create a task", setup: addCommand},
+	{name: "update", args: "<id>", summary: "change description, priority, due date, tags, assignee, estimate or parent of a task", setup: updateCommand},
+	{name: "mark", args: "<id> <status>", summary: "move a task to todo, in_progress, blocked, done or cancelled", setup: markCommand},
+	{name: "done", args: "<id>...", summary: "mark tasks as done", setup: markManyCommand(types.StatusDone)},
+	{name: "reopen", args: "<id>...", summary: "reopen done tasks (back to todo)", setup: markManyCommand(types.StatusTodo)},
+	{name: "assign", args: "<id> <user>", summary: "make a user the assignee of a task (me for yourself, none for nobody)", setup: assignCommand},
+	{name: "start", args: "<id>", summary: "start your timer on a task and set it in progress, stopping the running one", setup: startCommand},
+	{name: "stop", summary: "stop your timer and record the time on its task", setup: stopCommand},
+	{name: "report", summary: "time spent per task and day, of today, -week or -from/-to", setup: reportCommand},
+	{name: "stats", summary: "created and completed tasks, lead time and burndown of the last 14 days, -week or -from/-to", setup: statsCommand},
+	{name: "depends", args: "<id>", summary: "make a task wait for other tasks (-on), or stop waiting (-remove)", setup: dependsCommand},
+	{name: "blocked", summary: "list open tasks waiting for tasks that are not done", setup: blockedCommand},
+	{name: "rm", args: "<id>...", summary: "move tasks to the trash", setup: rmCommand},
+	{name: "trash", args: "[ls]", summary: "list tasks in the trash", setup: trashCommand},
+	{name: "restore", args: "<id>...", summary: "take tasks out of the trash", setup: restoreCommand},
+	{name: "purge", args: "[<id>...]", summary: "delete tasks in the trash for good", setup: purgeCommand},
+	{name: "undo", args: "[n]", summary: "revert the last n changes (default 1)", setup: replayCommand((*task.JSONStore).Undo, "undo", "Undid")},
+	{name: "redo", args: "[n]", summary: "apply again the last n undone changes", setup: replayCommand((*task.JSONStore).Redo, "redo", "Redid")},
+	{name: "move", args: "<id>...", summary: "move tasks and their subtasks to another project (-to)", setup: moveCommand},
+	{name: "project", args: "add|ls|rm", summary: "manage projects, -p selects one for the other commands", setup: projectCommand},
+	{name: "show", args: "<id>", summary: "show one task", setup: showCommand},
+	{name: "history", args: "<id>", summary: "show who changed a task and how", setup: historyCommand},
+	{name: "search", args: "<words>", summary: "find tasks by words of their description, best matches first", setup: searchCommand},
+	{name: "ls", summary: "list tasks by date or by query", setup: lsCommand},
+	{name: "today", summary: "create due recurring tasks and list tasks created today", setup: todayCommand},
+	{name: "recur", args: "add|ls|rm", summary: "manage templates of recurring tasks", setup: recurCommand},
+	{name: "materialize", summary: "create the due tasks of recurring task templates", setup: materializeCommand},
+	{name: "serve", summary: "run the REST API server", setup: serveCommand},
+	{name: "fsck", summary: "check the JSON storage for broken files, duplicate ids and a stale index", setup: fsckCommand},
+	{name: "config", summary: "show the config file, profile and storage in use", setup: configCommand},
+	{name: "import", summary: "copy all tasks from the JSON storage into the -db file", setup: importCommand},
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func addCommand(fs *flag.FlagSet) runFunc {
+	descFlag := fs.String("desc", "", "description of the task (or pass it as arguments)")
+	doneFlag := fs.Bool("done", false, "create the task as already done, same as -status done")
+	statusFlag := fs.String("status", string(types.StatusTodo), "initial status")
+	parentFlag := fs.Int64("parent", 0, "make the task a subtask of this task")
+	attrs := taskAttrFlags(fs)
+	return func(a *app, args []string) error {
+		desc := *descFlag
+		if desc == "" {
+			desc = joinArgs(args)
+		} else if len(args) > 0 {
+			return usageError("pass the description either with -desc or as arguments")
+		}
+		if desc == "" {
+			return usageError("provide task description")
+		}
+		status, err := types.ParseStatus(*statusFlag)
+		if err != nil {
+			return usageError(err.Error())
+		}
+		if *doneFlag {
+			if anyFlagSet(fs, "status") && status != types.StatusDone {
+				return usageError("-done contradicts -status")
+			}
+			status = types.StatusDone
+		}
+		if *parentFlag < 0 {
+			return usageError("-parent has to be a task id")
+		}
+		t := &types.Task{Description: desc, Status: status, ParentID: *parentFlag}
+		if err := attrs(a, t); err != nil {
+			return err
+		}
+		if err := task.Add(a.store, t); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Created task %d\n", t.ID)
+		return nil
+	}
+}
+
+func updateCommand(fs *flag.FlagSet) runFunc {
+	descFlag := fs.String("desc", "", "new description of the task")
+	parentFlag := fs.Int64("parent", 0, "make the task a subtask of this task, 0 makes it a top-level task")
+	attrs := taskAttrFlags(fs)
+	return func(a *app, args []string) error {
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		if !anyFlagSet(fs, "desc", "priority", "due", "tags", "assignee", "estimate", "parent") {
+			return usageError("nothing to update: provide -desc, -priority, -due, -tags, -assignee, -estimate or -parent")
+		}
+		if *parentFlag < 0 {
+			return usageError("-parent has to be a task id")
+		}
+		if anyFlagSet(fs, "desc") && *descFlag == "" {
+			return usageError("description can not be empty")
+		}
+		t, err := a.store.Get(id)
+		if err != nil {
+			return err
+		}
+		if *descFlag != "" {
+			t.Description = *descFlag
+		}
+		if err := attrs(a, t); err != nil {
+			return err
+		}
+		if anyFlagSet(fs, "parent") {
+			t.ParentID = *parentFlag
+		}
+		t.UpdateAt = time.Now().Local()
+		return task.Save(a.store, t, false)
+	}
+}
+
+// taskAttrFlags registers -priority, -due, -tags, -assignee and -estimate on fs. The
+// returned function copies the flags that were given into a task.
+func taskAttrFlags(fs *flag.FlagSet) func(a *app, t *types.Task) error {
+	priorityFlag := fs.String("priority", "", "priority: low, normal, high or urgent")
+	dueFlag := fs.String("due", "", "due date, "+types.DateLayout+" (end of that day) or "+dueTimeLayout+"; none removes it")
+	tagsFlag := fs.String("tags", "", "comma separated tags, replacing the current ones; empty removes them")
+	assigneeFlag := fs.String("assignee", "", "user the task is assigned to, me for yourself; none removes it")
+	estimateFlag := fs.String("estimate", "", "estimate in points or hours, e.g. 3 or 0.5; none removes it")
+	return func(a *app, t *types.Task) error {
+		if anyFlagSet(fs, "priority") {
+			p, err := types.ParsePriority(*priorityFlag)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			t.Priority = p
+		}
+		if anyFlagSet(fs, "due") {
+			due, err := parseDue(*dueFlag)
+			if err != nil {
+				return err
+			}
+			t.DueAt = due
+		}
+		if anyFlagSet(fs, "tags") {
+			t.Tags = types.SplitTags(*tagsFlag)
+		}
+		if anyFlagSet(fs, "assignee") {
+			t.Assignee = strings.TrimSpace(a.userArg(*assigneeFlag))
+		}
+		if anyFlagSet(fs, "estimate") {
+			e, err := types.ParseEstimate(*estimateFlag)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			t.Estimate = e
+		}
+		return nil
+	}
+}
+
+const dueTimeLayout = "2006-01-02T15:04"
+
+// parseDue parses the -due flag. A date alone means the end of that day.
+func parseDue(s string) (time.Time, error) {
+	if s == "none" || s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := types.ParseDate(s); err == nil {
+		return d.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	d, err := time.ParseInLocation(dueTimeLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, usageErrorf("-due: expected a date like %s or %s", types.DateLayout, dueTimeLayout)
+	}
+	return d, nil
+}
+
+func markCommand(fs *flag.FlagSet) runFunc {
+	forceFlag := fs.Bool("force", false, "mark the task done even though tasks it depends on are open")
+	return func(a *app, args []string) error {
+		if len(args) != 2 {
+			return usageError("provide a task id and a status")
+		}
+		id, err := parseID(args[:1])
+		if err != nil {
+			return err
+		}
+		status, err := types.ParseStatus(args[1])
+		if err != nil {
+			return usageError(err.Error())
+		}
+		_, err = task.Mark(a.store, id, status, *forceFlag)
+		return err
+	}
+}
+
+func assignCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) != 2 {
+			return usageError("provide a task id and a user")
+		}
+		id, err := parseID(args[:1])
+		if err != nil {
+			return err
+		}
+		_, err = task.Assign(a.store, id, a.userArg(args[1]))
+		return err
+	}
+}
+
+// userArg resolves the user names of assign and -assignee: me is who runs the command
+// and none nobody.
+func (a *app) userArg(name string) string {
+	switch name {
+	case "me":
+		return a.user
+	case "none":
+		return ""
+	}
+	return name
+}
+
+func startCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		now := time.Now().Local()
+		stopped, err := task.Start(a.store, id, a.user, now)
+		if stopped != nil {
+			iv := stopped.TimeLog[len(stopped.TimeLog)-1]
+			fmt.Fprintf(a.out, "Stopped timer on task %d after %s\n", stopped.ID, utils.FormatSpent(iv.Duration(now)))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Started timer on task %d\n", id)
+		return nil
+	}
+}
+
+func stopCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		t, iv, err := task.Stop(a.store, a.user, time.Now().Local())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Stopped timer on task %d after %s, %s in total\n", t.ID, utils.FormatSpent(iv.Duration(iv.End)), utils.FormatSpent(t.TimeSpent()))
+		return nil
+	}
+}
+
+// rangeFlags registers -week, -from and -to on fs. The returned function resolves them
+// to whole local days, from included and to excluded; without them the range is the
+// last days days, today included.
+func rangeFlags(fs *flag.FlagSet, days int) func(now time.Time) (from, to time.Time, err error) {
+	weekFlag := fs.Bool("week", false, "this week, Monday to Sunday")
+	fromFlag := fs.String("from", "", "first day, "+types.DateLayout)
+	toFlag := fs.String("to", "", "last day, "+types.DateLayout+" (default: today)")
+	return func(now time.Time) (time.Time, time.Time, error) {
+		y, m, d := now.Date()
+		today := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+		switch {
+		case *weekFlag && (*fromFlag != "" || *toFlag != ""):
+			return time.Time{}, time.Time{}, usageError("-week excludes -from and -to")
+		case *weekFlag:
+			from := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+			return from, from.AddDate(0, 0, 7), nil
+		}
+		to := today.AddDate(0, 0, 1)
+		if *toFlag != "" {
+			last, err := types.ParseDate(*toFlag)
+			if err != nil {
+				return time.Time{}, time.Time{}, usageErrorf("-to: expected a date like %s", types.DateLayout)
+			}
+			to = last.AddDate(0, 0, 1)
+		}
+		from := to.AddDate(0, 0, -days)
+		if *fromFlag != "" {
+			var err error
+			if from, err = types.ParseDate(*fromFlag); err != nil {
+				return time.Time{}, time.Time{}, usageErrorf("-from: expected a date like %s", types.DateLayout)
+			}
+		}
+		if !to.After(from) {
+			return time.Time{}, time.Time{}, usageError("-to is before -from")
+		}
+		return from, to, nil
+	}
+}
+
+func reportCommand(fs *flag.FlagSet) runFunc {
+	dates := rangeFlags(fs, 1)
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		now := time.Now().Local()
+		from, to, err := dates(now)
+		if err != nil {
+			return err
+		}
+		// time spent on tasks moved to the trash since was spent all the same
+		all, err := a.store.List()
+		if err != nil {
+			return err
+		}
+		trashed, err := a.store.Trash()
+		if err != nil {
+			return err
+		}
+		entries := types.TimeReport(append(all, trashed...), from, to, now)
+		return utils.WriteTimeReport(a.out, a.format, entries, utils.TerminalWidth())
+	}
+}
+
+func statsCommand(fs *flag.FlagSet) runFunc {
+	dates := rangeFlags(fs, 14)
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		now := time.Now().Local()
+		from, to, err := dates(now)
+		if err != nil {
+			return err
+		}
+		// tasks created after the range can not have been completed in it, so the year
+		// index only has to lead to the month files up to its last day
+		tasks, err := a.store.Query(&types.Filter{To: to.AddDate(0, 0, -1)})
+		if err != nil {
+			return err
+		}
+		return utils.WriteStats(a.out, a.format, types.ComputeStats(tasks, from, to, now), utils.TerminalWidth())
+	}
+}
+
+func markManyCommand(status types.Status) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		forceFlag := fs.Bool("force", false, "mark tasks done even though tasks they depend on are open")
+		return func(a *app, args []string) error {
+			ids, err := parseIDs(args)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if _, err := task.Mark(a.store, id, status, *forceFlag); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func dependsCommand(fs *flag.FlagSet) runFunc {
+	onFlag := fs.String("on", "", "comma separated ids of the tasks to wait for")
+	removeFlag := fs.Bool("remove", false, "drop the dependencies on the -on tasks instead")
+	return func(a *app, args []string) error {
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		if *onFlag == "" {
+			return usageError("provide the tasks to depend on with -on")
+		}
+		ons, err := parseIDs(strings.Split(*onFlag, ","))
+		if err != nil {
+			return err
+		}
+		for _, on := range ons {
+			if _, err := task.Depend(a.store, id, on, *removeFlag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func blockedCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		arr, err := task.Blocked(a.store)
+		if err != nil {
+			return err
+		}
+		return a.writeTasks(arr)
+	}
+}
+
+func rmCommand(fs *flag.FlagSet) runFunc {
+	cascadeFlag := fs.Bool("r", false, "also move the subtasks of the tasks to the trash")
+	return func(a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if _, err := task.Remove(a.store, id, *cascadeFlag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// replayCommand runs undo or redo, which only the JSON storage keeps a history for.
+func replayCommand(replay func(s *task.JSONStore, n int) ([]task.UndoStep, error), name, done string) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		return func(a *app, args []string) error {
+			n := 1
+			switch {
+			case len(args) > 1:
+				return usageErrorf("unexpected arguments %q", args[1:])
+			case len(args) == 1:
+				v, err := strconv.Atoi(args[0])
+				if err != nil || v < 1 {
+					return usageErrorf("invalid number of steps %q", args[0])
+				}
+				n = v
+			}
+			if a.dbPath != "" {
+				return usageErrorf("%s works on the JSON storage only", name)
+			}
+			steps, err := replay(a.jsonStore, n)
+			for _, step := range steps {
+				fmt.Fprintf(a.out, "%s %s of task %d\n", done, step.Op, step.TaskID)
+			}
+			if err != nil {
+				return err
+			}
+			if len(steps) == 0 {
+				fmt.Fprintln(a.out, "Nothing to", name)
+			}
+			return nil
+		}
+	}
+}
+
+func trashCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 1 || (len(args) == 1 && args[0] != "ls") {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		arr, err := a.store.Trash()
+		if err != nil {
+			return err
+		}
+		return utils.WriteTasks(a.out, a.format, arr, utils.TerminalWidth())
+	}
+}
+
+func restoreCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := a.store.Restore(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func purgeCommand(fs *flag.FlagSet) runFunc {
+	olderFlag := fs.String("older-than", "", "purge everything deleted longer ago than this age, e.g. 30d, 12h, 2w; 0d empties the trash")
+	return func(a *app, args []string) error {
+		var ids []int64
+		switch {
+		case len(args) > 0 && *olderFlag != "":
+			return usageError("pass either ids or -older-than")
+		case len(args) > 0:
+			var err error
+			if ids, err = parseIDs(args); err != nil {
+				return err
+			}
+		case *olderFlag != "":
+			age, ok := query.ParseAge(*olderFlag)
+			if !ok {
+				return usageErrorf("-older-than: expected an age like 30d, got %q", *olderFlag)
+			}
+			trash, err := a.store.Trash()
+			if err != nil {
+				return err
+			}
+			cutoff := time.Now().Add(-age)
+			for _, t := range trash {
+				if !t.DeletedAt.After(cutoff) {
+					ids = append(ids, t.ID)
+				}
+			}
+		default:
+			return usageError("provide task ids or -older-than")
+		}
+		for _, id := range ids {
+			if err := a.store.Purge(id); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(a.out, "Purged %d tasks\n", len(ids))
+		return nil
+	}
+}
+
+func showCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		t, err := a.store.Get(id)
+		if err != nil {
+			return err
+		}
+		if a.format == utils.FormatPlain {
+			return a.writeTasks([]*types.Task{t})
+		}
+		return utils.WriteTask(a.out, a.format, t, utils.TerminalWidth())
+	}
+}
+
+func historyCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		id, err := parseID(args)
+		if err != nil {
+			return err
+		}
+		events, err := a.store.History(id)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 && a.format == utils.FormatPlain {
+			fmt.Fprintf(a.out, "No history recorded for task %d\n", id)
+			return nil
+		}
+		return utils.WriteEvents(a.out, a.format, events, utils.TerminalWidth())
+	}
+}
+
+func searchCommand(fs *flag.FlagSet) runFunc {
+	limitFlag := fs.Int("limit", 0, "show at most this many tasks")
+	return func(a *app, args []string) error {
+		q := joinArgs(args)
+		if q == "" {
+			return usageError("provide words to search for")
+		}
+		if *limitFlag < 0 {
+			return usageError("-limit can not be negative")
+		}
+		arr, err := task.Search(a.store, q, time.Now().Local())
+		if err != nil {
+			return err
+		}
+		if *limitFlag > 0 && len(arr) > *limitFlag {
+			arr = arr[:*limitFlag]
+		}
+		// ranked, so not as a tree
+		return utils.WriteTasks(a.out, a.format, arr, utils.TerminalWidth())
+	}
+}
+
+func lsCommand(fs *flag.FlagSet) runFunc {
+	now := time.Now().Local()
+	dayFlag := fs.Int("day", 0, "day of the month (default: whole month)")
+	monthFlag := fs.Int("m", int(now.Month()), "month")
+	yearFlag := fs.Int("y", now.Year(), "year")
+	fromFlag := fs.String("from", "", "first day of a date range, "+types.DateLayout)
+	toFlag := fs.String("to", "", "last day of a date range, "+types.DateLayout)
+	queryFlag := fs.String("q", "", "query, e.g. 'status:todo desc~deploy created>=2026-10-01 updated<7d'.\nWithout date flags it searches all tasks instead of the current month, as -mine and -assignee do")
+	sortFlag := fs.String("sort", "", "comma separated sort fields, '-' for descending, e.g. created,-id")
+	limitFlag := fs.Int("limit", 0, "show at most this many tasks")
+	offsetFlag := fs.Int("offset", 0, "skip this many tasks")
+	trashedFlag := fs.Bool("trashed", false, "include tasks in the trash")
+	mineFlag := fs.Bool("mine", false, "only tasks assigned to you, same as -assignee me")
+	assigneeFlag := fs.String("assignee", "", "only tasks assigned to this user, none for unassigned ones")
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		if *limitFlag < 0 || *offsetFlag < 0 {
+			return usageError("-limit and -offset can not be negative")
+		}
+		f, err := dateFilter(fs, *dayFlag, *monthFlag, *yearFlag, *fromFlag, *toFlag)
+		if err != nil {
+			return err
+		}
+		f.Trashed = *trashedFlag
+		pred, err := query.Parse(*queryFlag, time.Now().Local())
+		if err != nil {
+			return usageError(err.Error())
+		}
+		byAssignee := *mineFlag || anyFlagSet(fs, "assignee")
+		if byAssignee {
+			if *mineFlag && anyFlagSet(fs, "assignee") {
+				return usageError("-mine and -assignee exclude each other")
+			}
+			user := a.user
+			if !*mineFlag {
+				user = a.userArg(*assigneeFlag)
+			}
+			matches := pred
+			pred = func(t *types.Task) bool { return t.Assignee == user && matches(t) }
+		}
+		var cmp query.Compare
+		if *sortFlag != "" {
+			if cmp, err = query.ParseSort(*sortFlag); err != nil {
+				return usageError(err.Error())
+			}
+		}
+
+		var arr []*types.Task
+		if (*queryFlag != "" || byAssignee) && !anyFlagSet(fs, "day", "m", "y", "from", "to") {
+			arr, err = a.store.Query(&types.Filter{Trashed: f.Trashed}) // every date
+		} else {
+			arr, err = a.store.Query(f)
+		}
+		if err != nil {
+			return err
+		}
+		arr = query.Run(arr, pred, cmp, *offsetFlag, *limitFlag)
+		return a.writeTasks(arr)
+	}
+}
+
+// anyFlagSet reports whether one of the named flags was given on the command line.
+func anyFlagSet(fs *flag.FlagSet, names ...string) bool {
+	found := false
+	fs.Visit(func(fl *flag.Flag) {
+		for _, n := range names {
+			if fl.Name == n {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+// dateFilter validates the date flags of ls. With -from or -to the current month is no
+// longer implied: -day, -m and -y only narrow the range when given explicitly.
+func dateFilter(fs *flag.FlagSet, day, month, year int, from, to string) (*types.Filter, error) {
+	f := &types.Filter{Day: day, Month: month, Year: year}
+	if from != "" || to != "" {
+		if !anyFlagSet(fs, "m") {
+			f.Month = 0
+		}
+		if !anyFlagSet(fs, "y") {
+			f.Year = 0
+		}
+	}
+	if from != "" {
+		d, err := types.ParseDate(from)
+		if err != nil {
+			return nil, usageErrorf("-from: expected a date like %s", types.DateLayout)
+		}
+		f.From = d
+	}
+	if to != "" {
+		d, err := types.ParseDate(to)
+		if err != nil {
+			return nil, usageErrorf("-to: expected a date like %s", types.DateLayout)
+		}
+		f.To = d
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return nil, usageError("-to is before -from")
+	}
+	if f.Day < 0 || f.Day > 31 {
+		return nil, usageError("-day has to be between 1 and 31")
+	}
+	if f.Month < 0 || f.Month > 12 {
+		return nil, usageError("-m has to be between 1 and 12")
+	}
+	if f.Year < 0 {
+		return nil, usageError("-y has to be a positive year")
+	}
+	return f, nil
+}
+
+func todayCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		if a.dbPath == "" {
+			if _, err := a.jsonStore.Materialize(time.Now().Local()); err != nil {
+				return err
+			}
+		} else {
+			// the templates and the tasks they create are kept in the JSON storage
+			tpls, err := a.jsonStore.Templates()
+			if err != nil {
+				return err
+			}
+			if len(tpls) > 0 {
+				log.Printf("warning: recurring tasks are not created with -db, run today or materialize without it to create the tasks of %d templates", len(tpls))
+			}
+		}
+		arr, err := a.store.Query(types.NewFilter())
+		if err != nil {
+			return err
+		}
+		if err := a.writeTasks(arr); err != nil {
+			return err
+		}
+		if a.format == utils.FormatPlain {
+			fmt.Fprintln(a.out, "Total tasks:", len(arr))
+		}
+		return nil
+	}
+}
+
+func recurCommand(fs *flag.FlagSet) runFunc {
+	everyFlag := fs.String("every", "", "schedule of recur add: daily, weekdays, weekly:mon,thu, monthly:1 or cron:'0 9 * * 1-5'")
+	priorityFlag := fs.String("priority", "", "priority of the created tasks: low, normal, high or urgent")
+	tagsFlag := fs.String("tags", "", "comma separated tags of the created tasks")
+	return func(a *app, args []string) error {
+		if len(args) == 0 {
+			return usageError("provide add, ls or rm")
+		}
+		if a.dbPath != "" {
+			return usageError("recurring tasks work on the JSON storage only")
+		}
+		switch args[0] {
+		case "add":
+			desc := joinArgs(args[1:])
+			if desc == "" {
+				return usageError("provide task description")
+			}
+			if *everyFlag == "" {
+				return usageError("provide the schedule with -every")
+			}
+			if _, err := recur.Parse(*everyFlag); err != nil {
+				return usageError(err.Error())
+			}
+			tpl := &types.Template{Description: desc, Schedule: *everyFlag, Tags: types.SplitTags(*tagsFlag), Project: a.project}
+			if *priorityFlag != "" {
+				p, err := types.ParsePriority(*priorityFlag)
+				if err != nil {
+					return usageError(err.Error())
+				}
+				tpl.Priority = p
+			}
+			if err := a.jsonStore.AddTemplate(tpl); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "Created recurring task %d (%s)\n", tpl.ID, tpl.Schedule)
+			return nil
+		case "ls":
+			if len(args) > 1 {
+				return usageErrorf("unexpected arguments %q", args[1:])
+			}
+			templates, err := a.jsonStore.Templates()
+			if err != nil {
+				return err
+			}
+			if a.project != "" {
+				templates = slices.DeleteFunc(templates, func(tpl *types.Template) bool { return tpl.Project != a.project })
+			}
+			return utils.WriteTemplates(a.out, a.format, templates, utils.TerminalWidth())
+		case "rm":
+			ids, err := parseIDs(args[1:])
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := a.jsonStore.RemoveTemplate(id); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return usageErrorf("unknown recur command %q, expected add, ls or rm", args[0])
+		}
+	}
+}
+
+func projectCommand(fs *flag.FlagSet) runFunc {
+	descFlag := fs.String("desc", "", "description of project add")
+	return func(a *app, args []string) error {
+		if len(args) == 0 {
+			return usageError("provide add, ls or rm")
+		}
+		switch args[0] {
+		case "add":
+			if len(args) != 2 {
+				return usageError("provide the name of the project")
+			}
+			p := &types.Project{Name: args[1], Description: *descFlag}
+			if _, err := types.ParseProjectName(p.Name); err != nil {
+				return usageError(err.Error())
+			}
+			if err := a.jsonStore.AddProject(p); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "Created project %s\n", p.Name)
+			return nil
+		case "ls":
+			if len(args) > 1 {
+				return usageErrorf("unexpected arguments %q", args[1:])
+			}
+			projects, err := a.jsonStore.Projects()
+			if err != nil {
+				return err
+			}
+			all, err := a.store.List()
+			if err != nil {
+				return err
+			}
+			progress := task.ProjectProgress(all)
+			res := make([]utils.ProjectSummary, 0, len(projects))
+			for _, p := range projects {
+				if a.project == "" || p.Name == a.project {
+					res = append(res, utils.ProjectSummary{Project: p, Done: progress[p.Name].Done, Total: progress[p.Name].Total})
+				}
+			}
+			return utils.WriteProjects(a.out, a.format, res, utils.TerminalWidth())
+		case "rm":
+			if len(args) < 2 {
+				return usageError("provide the names of the projects")
+			}
+			s := a.store
+			if ps, ok := s.(*task.ProjectStore); ok {
+				s = ps.Store // the tasks of every project count
+			}
+			for _, name := range args[1:] {
+				if err := task.RemoveProject(a.jsonStore, s, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return usageErrorf("unknown project command %q, expected add, ls or rm", args[0])
+		}
+	}
+}
+
+func moveCommand(fs *flag.FlagSet) runFunc {
+	toFlag := fs.String("to", "", "project to move the tasks to")
+	return func(a *app, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		if !anyFlagSet(fs, "to") {
+			return usageError("provide the project with -to, -to \"\" takes the tasks out of their project")
+		}
+		to := *toFlag
+		if to != "" {
+			if to, err = types.ParseProjectName(to); err != nil {
+				return usageError(err.Error())
+			}
+			if err := a.checkProject(to); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			moved, err := task.Move(a.store, id, to)
+			if err != nil {
+				return err
+			}
+			if to == "" {
+				fmt.Fprintf(a.out, "Took %d tasks out of their project\n", len(moved))
+				continue
+			}
+			fmt.Fprintf(a.out, "Moved %d tasks to %s\n", len(moved), to)
+		}
+		return nil
+	}
+}
+
+func materializeCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		if a.dbPath != "" {
+			return usageError("recurring tasks work on the JSON storage only")
+		}
+		created, err := a.jsonStore.Materialize(time.Now().Local())
+		for _, t := range created {
+			fmt.Fprintf(a.out, "Created task %d\n", t.ID)
+		}
+		return err
+	}
+}
+
+func configCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		if a.format == utils.FormatJSON || a.format == utils.FormatNDJSON {
+			return json.NewEncoder(a.out).Encode(a.settings)
+		}
+		cfg := a.settings.ConfigPath
+		if _, err := os.Stat(cfg); errors.Is(err, os.ErrNotExist) {
+			cfg += " (not found)"
+		}
+		fmt.Fprintf(a.out, "config: %v\n", cfg)
+		if a.settings.Profile != "" {
+			fmt.Fprintf(a.out, "profile: %v\n", a.settings.Profile)
+		}
+		fmt.Fprintf(a.out, "store: %v\n", a.settings.Store)
+		if a.settings.DB != "" {
+			fmt.Fprintf(a.out, "db: %v\n", a.settings.DB)
+		}
+		fmt.Fprintf(a.out, "user: %v\n", a.settings.User)
+		return nil
+	}
+}
+
+func fsckCommand(fs *flag.FlagSet) runFunc {
+	fixFlag := fs.Bool("fix", false, "repair what is found")
+	rebuildFlag := fs.Bool("rebuild-index", false, "regenerate the index from the month files")
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		if a.dbPath != "" {
+			return usageError("fsck checks the JSON storage only")
+		}
+		problems, err := a.jsonStore.Fsck(*fixFlag, *rebuildFlag)
+		if err != nil {
+			return err
+		}
+		switch a.format {
+		case utils.FormatJSON:
+			enc := json.NewEncoder(a.out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(problems); err != nil {
+				return err
+			}
+		case utils.FormatNDJSON:
+			enc := json.NewEncoder(a.out)
+			for _, p := range problems {
+				if err := enc.Encode(p); err != nil {
+					return err
+				}
+			}
+		default:
+			for _, p := range problems {
+				fmt.Fprintln(a.out, p)
+			}
+		}
+		open := 0
+		for _, p := range problems {
+			if !p.Fixed && !p.Note {
+				open++
+			}
+		}
+		if open > 0 {
+			return fmt.Errorf("%d problems found, run fsck -fix to repair them", open)
+		}
+		if a.format == utils.FormatPlain && len(problems) == 0 {
+			fmt.Fprintln(a.out, "No problems found")
+		}
+		return nil
+	}
+}
+
+func importCommand(fs *flag.FlagSet) runFunc {
+	return func(a *app, args []string) error {
+		if a.project != "" {
+			return usageError("import copies the tasks of every project, leave out -p")
+		}
+		dbStore, ok := a.store.(*task.DBStore)
+		if !ok {
+			return usageError("import needs the global -db flag: taskTracker -db tasks.db import")
+		}
+		n, err := task.ImportJSON(a.jsonStore, dbStore)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Imported %d tasks into %s\n", n, a.dbPath)
+		return nil
+	}
+}
+
+// writeTasks writes tasks in the -o format. The plain format shows them as a tree with
+// the progress of their subtasks, which needs every stored task.
+func (a *app) writeTasks(tasks []*types.Task) error {
+	var progress map[int64]types.Progress
+	if a.format == utils.FormatPlain {
+		all, err := a.store.List()
+		if err != nil {
+			return err
+		}
+		progress = task.Progress(all)
+	}
+	return utils.WriteTree(a.out, a.format, tasks, progress, utils.TerminalWidth())
+}
@@ -4,141 +4,201 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"taskTracker/pkg/config"
 	"taskTracker/pkg/task"
 	"taskTracker/pkg/types"
 	"taskTracker/pkg/utils"
-
-	"log"
+	"time"
 )
 
+// Exit codes. flag uses 2 for bad usage already, the rest follow it.
 const (
-	STORAGE_LAST_ID = "storage/lastID.json"
-	TASK_STORAGE    = "storage/tasks"
-	INDEX_STORAGE   = "storage/index"
-	LOG_STORAGE     = "storage/logs"
+	exitOK       = 0
+	exitError    = 1 // anything unexpected: I/O errors, corrupted files
+	exitUsage    = 2 // unknown command, bad flags or arguments
+	exitNotFound = 3 // the task does not exist
+	exitBusy     = 4 // another process kept the storage locked
+	exitConflict = 5 // the change breaks a rule, e.g. an illegal status transition
 )
 
 func main() {
-	if err := utils.SetStorage(TASK_STORAGE, INDEX_STORAGE, LOG_STORAGE); err != nil {
-		log.Fatal(err)
-	}
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	if err := execute(); err != nil{
-		log.Fatal(err)
-	}
+// app is what every command runs against.
+type app struct {
+	store     task.Store
+	jsonStore *task.JSONStore
+	settings  *config.Settings
+	dbPath    string
+	project   string // -p, a.store only holds the tasks of this project then
+	user      string // who runs the command, recorded as creator and in the audit log
+	wait      time.Duration
+	out       io.Writer
+	format    utils.Format
 }
 
-func execute() error {
-	fPath := utils.GetTargetPath(TASK_STORAGE)
-	lastID, err := utils.ReadLastID(STORAGE_LAST_ID)
+func run(args []string, stdout, stderr io.Writer) int {
+	log.SetOutput(stderr)
+	fs := flag.NewFlagSet("taskTracker", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dbFlag := fs.String("db", "", "path to an embedded database file to use instead of the JSON storage (env "+config.EnvDB+")")
+	storeFlag := fs.String("store", "", "directory of the JSON storage (env "+config.EnvStore+")")
+	profileFlag := fs.String("profile", "", "profile of the config file to use (env "+config.EnvProfile+")")
+	projectFlag := fs.String("p", "", "limit the command to the tasks of this project; new tasks go into it")
+	waitFlag := fs.Duration("wait", task.DefaultLockTimeout, "how long to wait while another process is using the storage")
+	formatFlag := fs.String("o", string(utils.FormatPlain), "output format of show, ls, today and history: "+formatNames())
+	fs.Usage = func() { mainUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	format, err := utils.ParseFormat(*formatFlag)
 	if err != nil {
-		return err
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
 	}
 
-	createFlag := flag.Bool("c", false, "create task")
-	updateFlag := flag.Bool("u", false, "update task. used with -id flag")
-	deleteFlag := flag.Bool("d", false, "delete task. used with -id flag")
-	getByIDFlag := flag.Bool("g", false, "get one task by id. use with -id flag")
-	getTodayFlag := flag.Bool("today", false, "get todays tasks")
-	descFlag := flag.String("desc", "", "description fot your task")
-	doneFlag := flag.Bool("done", false, "task status (done or not)")
-	idFlag := flag.Int64("id", 0, "indicate in case you want to update a task")
-
-	listFlag := flag.Bool("ld", false, "get tasks according to the -day, -m, -y")
-	dayFlag := flag.Int("day", -1, "indicate day")
-	monthFlag := flag.Int("m", -1, "indicate month")
-	yearFlag := flag.Int("y", -1, "indicate year")
-
-	helpFlag := flag.Bool("h", false, "help")
-
-	flag.Parse()
-	if *helpFlag {
-		utils.Help()
+	name, args := fs.Arg(0), fs.Args()[1:]
+	if name == "help" {
+		return help(fs, args)
+	}
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(fs.Output(), "unknown command %q\n\n", name)
+		fs.Usage()
+		return exitUsage
 	}
 
-	if *createFlag {
-		if *descFlag == "" {
-			return errors.New("provide task description")
-		}
-		if err := task.CreateTask(fPath, *descFlag, *doneFlag, lastID); err != nil {
-			return err
+	cfs := newCommandFlagSet(cmd, stderr)
+	runCmd := cmd.setup(cfs)
+	pos, err := parseInterspersed(cfs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
+		return exitUsage
 	}
-	if *updateFlag && *idFlag > 0 {
-		targetFile, err := task.SearchByID(*idFlag, INDEX_STORAGE, TASK_STORAGE)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				fmt.Println("task does not exist")
-				return nil
-			}
-			return err
-		}
-		if err := task.Update(*idFlag, *doneFlag, *descFlag, targetFile); err != nil {
-			log.Fatal(err)
-		}
+
+	settings, err := config.Resolve(config.Profile{Store: *storeFlag, DB: *dbFlag}, *profileFlag)
+	if err != nil {
+		log.Println(err)
+		return exitError
 	}
-	if *deleteFlag && *idFlag > 0 {
-		targetFile, err := task.SearchByID(*idFlag, INDEX_STORAGE, TASK_STORAGE)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				fmt.Println("task does not exist")
-				return os.ErrNotExist
-			}
-			return err
-		}
-		if err := task.Delete(*idFlag, targetFile); err != nil {
-			log.Fatal(err)
-		}
+	if settings.Legacy != "" {
+		log.Printf("warning: using %s, which is empty, and not %s of earlier versions; run with -store %s or set \"store\" in %s",
+			settings.Store, settings.Legacy, config.LegacyStore, settings.ConfigPath)
 	}
-	if *getTodayFlag {
-		targetFile, err := task.SearchByID(lastID, INDEX_STORAGE, TASK_STORAGE)
-		if err != nil {
-			return err
-		}
-		arr, err := task.GetToday(lastID, targetFile)
-		if err != nil {
-			return err
+	if settings.User == "" {
+		settings.User = utils.CurrentUser()
+	}
+	a := &app{settings: settings, dbPath: settings.DB, user: settings.User, wait: *waitFlag, out: stdout, format: format}
+	var closeStore func() error
+	a.store, a.jsonStore, closeStore, err = openStore(settings.Store, a.dbPath, a.user, a.wait)
+	if err != nil {
+		return exitCode(cfs, err)
+	}
+	defer closeStore()
+	if *projectFlag != "" {
+		if err := a.selectProject(*projectFlag); err != nil {
+			return exitCode(fs, err)
 		}
+	}
 
-		for _, t := range arr {
-			utils.ShowTask(*t)
-		}
-		fmt.Println("Total tasks:", len(arr))
+	return exitCode(cfs, runCmd(a, pos))
+}
+
+// selectProject limits a.store to the tasks of a project of the JSON storage.
+func (a *app) selectProject(name string) error {
+	name, err := types.ParseProjectName(name)
+	if err != nil {
+		return usageError("-p: " + err.Error())
 	}
+	if err := a.checkProject(name); err != nil {
+		return err
+	}
+	a.project = name
+	a.store = &task.ProjectStore{Store: a.store, Project: name}
+	a.jsonStore.UndoProject = name
+	return nil
+}
 
-	if *getByIDFlag && *idFlag > 0 {
-		targetFile, err := task.SearchByID(*idFlag, INDEX_STORAGE, TASK_STORAGE)
-		if err != nil {
-			return err
-		}
-		t, err := task.GetByID(*idFlag, targetFile)
-		if err != nil {
-			return err
+// checkProject fails with a task.NotFoundError, exit code 3, for a project that was
+// never added.
+func (a *app) checkProject(name string) error {
+	if _, err := a.jsonStore.Project(name); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w, add it with 'taskTracker project add %s'", err, name)
 		}
-		utils.ShowTask(*t)
+		return err
 	}
+	return nil
+}
 
-	if *listFlag {
-		f := types.NewFilter()
-		if *dayFlag > 0 && *dayFlag < 32 {
-			f.Day = *dayFlag
-		}
-		if *monthFlag > 0 && *monthFlag < 13 {
-			f.Month = *monthFlag
-		}
-		if *yearFlag > 2025 {
-			f.Year = *yearFlag
-		}
-		arr, err := task.GetByDate(TASK_STORAGE, f)
-		if err != nil {
-			return err
-		}
-		for _, elem := range arr {
-			utils.ShowTask(*elem)
-		}
+// exitCode reports err and maps it to one of the exit codes above.
+func exitCode(fs *flag.FlagSet, err error) int {
+	var uErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &uErr):
+		fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		return exitUsage
+	case errors.Is(err, os.ErrNotExist):
+		var nf *task.NotFoundError
+		if errors.As(err, &nf) {
+			log.Println(err)
+		} else {
+			log.Println("task does not exist")
+		}
+		return exitNotFound
+	case errors.Is(err, utils.ErrStorageBusy):
+		log.Println(err)
+		return exitBusy
+	case task.IsConflict(err):
+		log.Println(err)
+		return exitConflict
+	default:
+		log.Println(err)
+		return exitError
 	}
+}
 
-	log.Println("Finished successfully")
-	return nil
+// openStore returns the JSON storage in root, or the -db file when dbPath is set, after
+// finishing any change a crashed run left behind. Changes are recorded as done by user.
+// close has to be called when done.
+func openStore(root, dbPath, user string, wait time.Duration) (store task.Store, jsonStore *task.JSONStore, close func() error, err error) {
+	jsonStore = task.NewJSONStoreIn(root)
+	jsonStore.LockTimeout = wait
+	jsonStore.Actor = user
+	if err := utils.SetStorage(jsonStore.TaskDir, jsonStore.IndexDir, jsonStore.LogDir); err != nil {
+		return nil, nil, nil, err
+	}
+	recovered, err := jsonStore.Recover()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if recovered {
+		log.Println("Finished a change interrupted by a previous run")
+	}
+	if dbPath == "" {
+		return jsonStore, jsonStore, func() error { return nil }, nil
+	}
+	dbStore, err := task.OpenDBStore(dbPath, wait)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dbStore.Actor = user
+	return dbStore, jsonStore, dbStore.Close, nil
 }
@@ -0,0 +1,628 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"taskTracker/pkg/config"
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCLI runs the command line in a fresh working directory shared by one test.
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// isolate keeps the test away from the config and the tasks of the user running it.
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(t.TempDir(), "data"))
+	for _, env := range []string{config.EnvConfig, config.EnvProfile, config.EnvStore, config.EnvDB, config.EnvUser} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestRun(t *testing.T) {
+	isolate(t)
+	t.Setenv(config.EnvStore, "storage")
+
+	t.Run("no command -> usage", func(t *testing.T) {
+		code, _, stderr := runCLI(t)
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "commands:")
+	})
+
+	t.Run("unknown command -> usage", func(t *testing.T) {
+		code, _, stderr := runCLI(t, "frobnicate")
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, `unknown command "frobnicate"`)
+	})
+
+	t.Run("help of a command lists its flags", func(t *testing.T) {
+		code, _, stderr := runCLI(t, "help", "add")
+		assert.Equal(t, exitOK, code)
+		assert.Contains(t, stderr, "usage: taskTracker add [flags] [description]")
+		assert.Contains(t, stderr, "-desc")
+
+		code, _, _ = runCLI(t, "rm", "-h")
+		assert.Equal(t, exitOK, code)
+	})
+
+	t.Run("add", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "add", "-desc", "first")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "Created task 1\n", stdout)
+
+		code, stdout, _ = runCLI(t, "add", "second", "task")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "Created task 2\n", stdout)
+	})
+
+	t.Run("add without description -> usage", func(t *testing.T) {
+		code, _, stderr := runCLI(t, "add")
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "provide task description")
+	})
+
+	t.Run("update takes flags after the id", func(t *testing.T) {
+		code, _, _ := runCLI(t, "update", "2", "-desc", "renamed")
+		require.Equal(t, exitOK, code)
+		_, stdout, _ := runCLI(t, "show", "2")
+		assert.Contains(t, stdout, "2. renamed / status: todo")
+	})
+
+	t.Run("update without changes -> usage", func(t *testing.T) {
+		code, _, _ := runCLI(t, "update", "2")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("done and reopen", func(t *testing.T) {
+		code, _, _ := runCLI(t, "done", "1", "2")
+		require.Equal(t, exitOK, code)
+		_, stdout, _ := runCLI(t, "show", "1")
+		assert.Contains(t, stdout, "status: done")
+
+		code, _, _ = runCLI(t, "reopen", "1")
+		require.Equal(t, exitOK, code)
+		_, stdout, _ = runCLI(t, "show", "1")
+		assert.Contains(t, stdout, "status: todo")
+	})
+
+	t.Run("mark", func(t *testing.T) {
+		code, _, _ := runCLI(t, "mark", "1", "in_progress")
+		require.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "mark", "1", "cancelled")
+		require.Equal(t, exitOK, code)
+		code, _, stderr := runCLI(t, "done", "1")
+		assert.Equal(t, exitConflict, code)
+		assert.Contains(t, stderr, "can not go from cancelled to done")
+
+		code, _, _ = runCLI(t, "mark", "1", "todo")
+		require.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "mark", "1", "finished")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "mark", "1")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("today and ls", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "today")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "Total tasks: 2")
+
+		code, stdout, _ = runCLI(t, "ls")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "1. first")
+		assert.Contains(t, stdout, "2. renamed")
+
+		code, _, _ = runCLI(t, "ls", "-m", "13")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("ls with a date range", func(t *testing.T) {
+		today := time.Now().Local()
+		code, stdout, _ := runCLI(t, "ls", "-from", today.AddDate(-1, 0, 0).Format(types.DateLayout), "-to", today.Format(types.DateLayout))
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "1. first")
+
+		code, stdout, _ = runCLI(t, "ls", "-to", today.AddDate(0, 0, -1).Format(types.DateLayout))
+		require.Equal(t, exitOK, code)
+		assert.Empty(t, stdout)
+
+		code, _, _ = runCLI(t, "ls", "-from", "yesterday")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "ls", "-from", "2026-02-01", "-to", "2026-01-01")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("priority, due date and tags", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "add", "-priority", "high", "-due", "2030-01-31", "-tags", "Ops, billing,ops", "pay invoices")
+		require.Equal(t, exitOK, code)
+		require.Equal(t, "Created task 3\n", stdout)
+
+		_, stdout, _ = runCLI(t, "-o", "ndjson", "show", "3")
+		assert.Contains(t, stdout, `"priority":"high"`)
+		assert.Contains(t, stdout, `"tags":["ops","billing"]`)
+		assert.Contains(t, stdout, `"due_at":"2030-01-31T23:59:59`)
+
+		code, _, _ = runCLI(t, "update", "3", "-priority", "urgent", "-tags", "", "-due", "none")
+		require.Equal(t, exitOK, code)
+		_, stdout, _ = runCLI(t, "-o", "ndjson", "show", "3")
+		assert.Contains(t, stdout, `"priority":"urgent"`)
+		assert.NotContains(t, stdout, "tags")
+		assert.NotContains(t, stdout, "due_at")
+
+		code, _, _ = runCLI(t, "add", "-priority", "asap", "x")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "add", "-due", "friday", "x")
+		assert.Equal(t, exitUsage, code)
+
+		code, _, _ = runCLI(t, "update", "3", "-tags", "ops")
+		require.Equal(t, exitOK, code)
+		code, stdout, _ = runCLI(t, "ls", "-q", "priority>=high tag:ops", "-sort", "-priority")
+		require.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(stdout, "3. pay invoices"))
+		assert.Equal(t, 1, strings.Count(stdout, "\n"))
+
+		code, _, _ = runCLI(t, "rm", "3")
+		require.Equal(t, exitOK, code)
+	})
+
+	t.Run("ls with a query", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "ls", "-q", "desc~REN done:true")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, 1, strings.Count(stdout, "\n"))
+		assert.Contains(t, stdout, "2. renamed")
+
+		code, stdout, _ = runCLI(t, "ls", "-q", "created<7d", "-sort", "-id", "-limit", "1")
+		require.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(stdout, "2. renamed"))
+		assert.Equal(t, 1, strings.Count(stdout, "\n"))
+
+		code, _, _ = runCLI(t, "ls", "-q", "color:red")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "ls", "-sort", "color")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("output formats", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "-o", "json", "show", "2")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, `"description": "renamed"`)
+
+		code, stdout, _ = runCLI(t, "-o", "csv", "today")
+		require.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(stdout, "id,status,done,created_at,updated_at,priority,due_at,done_at,project,created_by,assignee,estimate,tags,parent_id,depends_on,deleted_at,description\n"))
+		assert.NotContains(t, stdout, "Total tasks")
+
+		code, _, _ = runCLI(t, "-o", "xml", "today")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("subtasks", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "add", "-parent", "1", "write notes")
+		require.Equal(t, exitOK, code)
+		code, _, stderr := runCLI(t, "add", "-parent", "2", "more")
+		assert.Equal(t, exitConflict, code)
+		assert.Contains(t, stderr, "task 2 is done")
+		id := strings.TrimSuffix(strings.TrimPrefix(stdout, "Created task "), "\n")
+		code, _, _ = runCLI(t, "add", "-parent", "99", "orphan")
+		assert.Equal(t, exitConflict, code)
+
+		_, stdout, _ = runCLI(t, "ls", "-q", "parent:1")
+		assert.Contains(t, stdout, id+". write notes / status: todo / parent: 1")
+		_, stdout, _ = runCLI(t, "show", "1")
+		assert.Contains(t, stdout, "/ 0/1 subtasks done")
+
+		code, _, stderr = runCLI(t, "rm", "1")
+		assert.Equal(t, exitConflict, code)
+		assert.Contains(t, stderr, "task has subtasks")
+		code, _, _ = runCLI(t, "update", id, "-parent", "0")
+		require.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "rm", id)
+		require.Equal(t, exitOK, code)
+	})
+
+	t.Run("dependencies", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "add", "deploy")
+		require.Equal(t, exitOK, code)
+		id := strings.TrimSuffix(strings.TrimPrefix(stdout, "Created task "), "\n")
+
+		code, _, _ = runCLI(t, "depends", id, "-on", "1")
+		require.Equal(t, exitOK, code)
+		code, _, stderr := runCLI(t, "depends", "1", "-on", id)
+		assert.Equal(t, exitConflict, code)
+		assert.Contains(t, stderr, "dependency cycle")
+
+		_, stdout, _ = runCLI(t, "blocked")
+		assert.Contains(t, stdout, id+". deploy / status: todo / depends on: 1")
+		code, _, _ = runCLI(t, "done", id)
+		assert.Equal(t, exitConflict, code)
+		code, _, _ = runCLI(t, "done", "-force", id)
+		require.Equal(t, exitOK, code)
+		_, stdout, _ = runCLI(t, "blocked")
+		assert.Empty(t, stdout)
+	})
+
+	t.Run("rm", func(t *testing.T) {
+		code, _, _ := runCLI(t, "rm", "1")
+		require.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "show", "1")
+		assert.Equal(t, exitNotFound, code)
+	})
+
+	t.Run("trash, restore and purge", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "trash", "ls")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "1. first")
+		assert.Contains(t, stdout, "/ deleted: ")
+
+		_, stdout, _ = runCLI(t, "ls", "-q", "id:1")
+		assert.Empty(t, stdout)
+		_, stdout, _ = runCLI(t, "ls", "-trashed", "-q", "id:1")
+		assert.Contains(t, stdout, "1. first")
+
+		code, _, _ = runCLI(t, "restore", "1")
+		require.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "show", "1")
+		assert.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "restore", "1")
+		assert.Equal(t, exitNotFound, code)
+
+		code, _, _ = runCLI(t, "rm", "1")
+		require.Equal(t, exitOK, code)
+		code, stdout, _ = runCLI(t, "purge", "-older-than", "30d")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "Purged 0 tasks\n", stdout)
+		code, _, _ = runCLI(t, "purge")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("undo and redo", func(t *testing.T) {
+		code, _, _ := runCLI(t, "add", "mistake")
+		require.Equal(t, exitOK, code)
+		code, _, _ = runCLI(t, "rm", "2")
+		require.Equal(t, exitOK, code)
+
+		code, stdout, _ := runCLI(t, "undo", "2")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "Undid delete of task 2\nUndid create of task")
+		_, stdout, _ = runCLI(t, "show", "2")
+		assert.Contains(t, stdout, "2. renamed")
+
+		code, stdout, _ = runCLI(t, "redo")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "Redid create of task")
+		code, _, _ = runCLI(t, "undo", "0")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "-db", "storage/tasks.db", "undo")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "history", "1")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "create task 1\n    description: first\n")
+		assert.Contains(t, stdout, "    status: todo -> done\n")
+		assert.Contains(t, stdout, "delete task 1\n")
+
+		code, stdout, _ = runCLI(t, "-o", "csv", "history", "1")
+		require.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(stdout, "at,actor,op,task_id,field,old,new\n"))
+
+		code, _, _ = runCLI(t, "history", "99")
+		assert.Equal(t, exitNotFound, code)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		code, _, _ := runCLI(t, "add", "Postgres migration plan")
+		require.Equal(t, exitOK, code)
+		code, stdout, _ := runCLI(t, "search", "postgres", "MIGR")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, ". Postgres migration plan / status: todo")
+		code, stdout, _ = runCLI(t, "search", "nothing-like-this")
+		require.Equal(t, exitOK, code)
+		assert.Empty(t, stdout)
+		code, _, _ = runCLI(t, "search")
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("recurring tasks", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "recur", "add", "-every", "daily", "-tags", "team", "standup")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "Created recurring task 1 (daily)\n", stdout)
+		code, _, _ = runCLI(t, "recur", "add", "-every", "hourly", "standup")
+		assert.Equal(t, exitUsage, code)
+
+		code, stdout, _ = runCLI(t, "today")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, ". standup / status: todo / tags: team")
+		code, stdout, _ = runCLI(t, "materialize")
+		require.Equal(t, exitOK, code)
+		assert.Empty(t, stdout, "today already created the task of this day")
+		code, _, stderr := runCLI(t, "-db", "tasks.db", "today")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stderr, "recurring tasks are not created with -db")
+
+		code, stdout, _ = runCLI(t, "recur", "ls")
+		require.Equal(t, exitOK, code)
+		assert.True(t, strings.HasPrefix(stdout, "1. standup / every: daily / tags: team / last: "))
+		code, _, _ = runCLI(t, "recur", "rm", "1")
+		require.Equal(t, exitOK, code)
+		code, _, stderr = runCLI(t, "recur", "rm", "1")
+		assert.Equal(t, exitNotFound, code)
+		assert.Contains(t, stderr, "recurring task template 1 does not exist")
+	})
+
+	t.Run("fsck", func(t *testing.T) {
+		code, stdout, _ := runCLI(t, "fsck")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "No problems found\n", stdout)
+
+		require.NoError(t, os.RemoveAll(filepath.Join("storage", "index")))
+		code, stdout, _ = runCLI(t, "ls")
+		require.Equal(t, exitOK, code)
+		assert.NotContains(t, stdout, "2. renamed", "listing by date needs the year index")
+		code, stdout, stderr := runCLI(t, "fsck")
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stdout, "orphan: ")
+		assert.Contains(t, stderr, "run fsck -fix")
+
+		code, _, _ = runCLI(t, "fsck", "-rebuild-index")
+		require.Equal(t, exitOK, code)
+		_, stdout, _ = runCLI(t, "ls")
+		assert.Contains(t, stdout, "2. renamed")
+	})
+
+	t.Run("bad ids -> usage, missing ids -> not found", func(t *testing.T) {
+		code, _, _ := runCLI(t, "show", "abc")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "rm")
+		assert.Equal(t, exitUsage, code)
+		code, _, _ = runCLI(t, "done", "99")
+		assert.Equal(t, exitNotFound, code)
+	})
+
+	t.Run("import needs -db", func(t *testing.T) {
+		code, _, _ := runCLI(t, "import")
+		assert.Equal(t, exitUsage, code)
+
+		code, stdout, _ := runCLI(t, "-db", "storage/tasks.db", "import")
+		require.Equal(t, exitOK, code)
+		assert.Contains(t, stdout, "Imported 8 tasks")
+	})
+}
+
+func TestStorageLocation(t *testing.T) {
+	isolate(t)
+	dataDir := os.Getenv("XDG_DATA_HOME")
+
+	t.Run("one store whatever the working directory", func(t *testing.T) {
+		code, _, _ := runCLI(t, "add", "from the root")
+		require.Equal(t, exitOK, code)
+		require.NoError(t, os.Mkdir("sub", 0755))
+		t.Chdir("sub")
+		_, stdout, _ := runCLI(t, "show", "1")
+		assert.Contains(t, stdout, "1. from the root")
+		_, err := os.Stat(filepath.Join(dataDir, "taskTracker", "lastID.json"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("-store and the environment", func(t *testing.T) {
+		code, _, _ := runCLI(t, "-store", "elsewhere", "show", "1")
+		assert.Equal(t, exitNotFound, code)
+		t.Setenv(config.EnvStore, "elsewhere")
+		code, stdout, _ := runCLI(t, "add", "elsewhere")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "Created task 1\n", stdout)
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		_, stdout, _ = runCLI(t, "config")
+		assert.Contains(t, stdout, "\nstore: "+filepath.Join(wd, "elsewhere")+"\n")
+	})
+
+	t.Run("profiles", func(t *testing.T) {
+		cfg := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "taskTracker", "config.json")
+		require.NoError(t, os.MkdirAll(filepath.Dir(cfg), 0755))
+		require.NoError(t, os.WriteFile(cfg, []byte(`{"profile": "work", "profiles": {"work": {"store": "work"}, "personal": {"user": "alice"}}}`), 0644))
+
+		code, _, _ := runCLI(t, "add", "work task")
+		require.Equal(t, exitOK, code)
+		_, err := os.Stat(filepath.Join(filepath.Dir(cfg), "work", "tasks"))
+		assert.NoError(t, err, "relative stores of the config file are relative to it")
+
+		code, stdout, _ := runCLI(t, "-profile", "personal", "config")
+		require.Equal(t, exitOK, code)
+		assert.Equal(t, "config: "+cfg+"\nprofile: personal\nstore: "+filepath.Join(dataDir, "taskTracker", "profiles", "personal")+"\nuser: alice\n", stdout)
+
+		code, _, stderr := runCLI(t, "-profile", "home", "ls")
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr, `unknown profile "home", expected one of personal, work`)
+	})
+}
+
+func TestLegacyStorage(t *testing.T) {
+	isolate(t)
+	code, _, _ := runCLI(t, "-store", "storage", "add", "from an earlier version")
+	require.Equal(t, exitOK, code)
+
+	code, _, stderr := runCLI(t, "ls")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stderr, "warning: using "+filepath.Join(os.Getenv("XDG_DATA_HOME"), "taskTracker")+", which is empty")
+	assert.Contains(t, stderr, "run with -store storage")
+	_, stdout, stderr := runCLI(t, "-store", "storage", "ls", "-q", "")
+	assert.Contains(t, stdout, "from an earlier version")
+	assert.Empty(t, stderr)
+}
+
+func TestProjects(t *testing.T) {
+	isolate(t)
+
+	code, stdout, _ := runCLI(t, "project", "add", "-desc", "invoices", "billing")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, "Created project billing\n", stdout)
+	code, _, _ = runCLI(t, "project", "add", "auth")
+	require.Equal(t, exitOK, code)
+	code, _, stderr := runCLI(t, "-p", "search", "ls")
+	assert.Equal(t, exitNotFound, code)
+	assert.Contains(t, stderr, `project "search" does not exist, add it with 'taskTracker project add search'`)
+	code, _, _ = runCLI(t, "-p", "no/such", "ls")
+	assert.Equal(t, exitUsage, code)
+
+	runCLI(t, "add", "global")
+	code, stdout, _ = runCLI(t, "-p", "billing", "add", "send invoices")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, "Created task 2\n", stdout, "ids are shared by all projects")
+	runCLI(t, "-p", "billing", "add", "-parent", "2", "print them")
+	runCLI(t, "-p", "auth", "add", "rotate keys")
+
+	_, stdout, _ = runCLI(t, "-p", "billing", "ls", "-q", "")
+	assert.Contains(t, stdout, "2. send invoices / status: todo / project: billing")
+	assert.NotContains(t, stdout, "rotate keys")
+	code, _, _ = runCLI(t, "-p", "billing", "show", "4")
+	assert.Equal(t, exitNotFound, code)
+	_, stdout, _ = runCLI(t, "ls", "-q", "project:auth")
+	assert.Contains(t, stdout, "4. rotate keys")
+
+	_, stdout, _ = runCLI(t, "project", "ls")
+	assert.Equal(t, "auth: 0/1 tasks done\nbilling: 0/2 tasks done / invoices\n", stdout)
+
+	code, stdout, _ = runCLI(t, "move", "-to", "auth", "2")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, "Moved 2 tasks to auth\n", stdout)
+	code, _, stderr = runCLI(t, "move", "-to", "nowhere", "2")
+	assert.Equal(t, exitNotFound, code)
+	assert.Contains(t, stderr, `project "nowhere" does not exist`)
+	code, _, stderr = runCLI(t, "project", "rm", "nowhere")
+	assert.Equal(t, exitNotFound, code)
+	assert.Contains(t, stderr, `project "nowhere" does not exist`)
+	assert.NotContains(t, stderr, "task")
+
+	code, _, stderr = runCLI(t, "project", "rm", "auth")
+	assert.Equal(t, exitConflict, code)
+	assert.Contains(t, stderr, "move or purge the 3 tasks of project auth first")
+	code, _, _ = runCLI(t, "project", "rm", "billing")
+	require.Equal(t, exitOK, code)
+	_, stdout, _ = runCLI(t, "project", "ls")
+	assert.Equal(t, "auth: 0/3 tasks done\n", stdout)
+
+	code, _, _ = runCLI(t, "move", "4")
+	assert.Equal(t, exitUsage, code)
+	code, stdout, _ = runCLI(t, "-p", "auth", "move", "-to", "", "4")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, "Took 1 tasks out of their project\n", stdout)
+	_, stdout, _ = runCLI(t, "project", "ls")
+	assert.Equal(t, "auth: 0/2 tasks done\n", stdout)
+}
+
+func TestAssign(t *testing.T) {
+	isolate(t)
+	t.Setenv(config.EnvUser, "alice")
+
+	runCLI(t, "add", "review budget")
+	code, _, _ := runCLI(t, "add", "-assignee", "me", "write report")
+	require.Equal(t, exitOK, code)
+	code, _, _ = runCLI(t, "assign", "1", "bob")
+	require.Equal(t, exitOK, code)
+	code, _, _ = runCLI(t, "assign", "9", "bob")
+	assert.Equal(t, exitNotFound, code)
+
+	_, stdout, _ := runCLI(t, "ls", "-mine")
+	assert.Equal(t, 1, strings.Count(stdout, "\n"))
+	assert.Contains(t, stdout, "2. write report / status: todo / assignee: alice")
+	_, stdout, _ = runCLI(t, "ls", "-assignee", "bob")
+	assert.Contains(t, stdout, "1. review budget / status: todo / assignee: bob")
+	assert.NotContains(t, stdout, "write report")
+	code, _, _ = runCLI(t, "ls", "-mine", "-assignee", "bob")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = runCLI(t, "update", "1", "-assignee", "none")
+	require.Equal(t, exitOK, code)
+	_, stdout, _ = runCLI(t, "ls", "-assignee", "none")
+	assert.Contains(t, stdout, "1. review budget / status: todo --- ")
+	_, stdout, _ = runCLI(t, "-o", "json", "show", "1")
+	assert.Contains(t, stdout, `"created_by": "alice"`)
+
+	t.Setenv(config.EnvUser, "bob")
+	runCLI(t, "assign", "1", "me")
+	_, stdout, _ = runCLI(t, "history", "1")
+	assert.Contains(t, stdout, " bob update task 1\n    assignee: (none) -> bob\n")
+}
+
+func TestTimeTracking(t *testing.T) {
+	isolate(t)
+	t.Setenv(config.EnvUser, "alice")
+
+	runCLI(t, "add", "review budget")
+	runCLI(t, "add", "write report")
+	code, stdout, _ := runCLI(t, "start", "1")
+	require.Equal(t, exitOK, code)
+	assert.Equal(t, "Started timer on task 1\n", stdout)
+	_, stdout, _ = runCLI(t, "show", "1")
+	assert.Contains(t, stdout, "status: in_progress")
+	assert.Contains(t, stdout, "timer: alice since ")
+
+	_, stdout, _ = runCLI(t, "start", "2")
+	assert.Contains(t, stdout, "Stopped timer on task 1 after 0h00m\nStarted timer on task 2\n")
+	code, stdout, _ = runCLI(t, "stop")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "Stopped timer on task 2 after 0h00m")
+	code, _, _ = runCLI(t, "stop")
+	assert.Equal(t, exitConflict, code)
+	code, _, _ = runCLI(t, "start", "9")
+	assert.Equal(t, exitNotFound, code)
+
+	code, stdout, _ = runCLI(t, "-o", "csv", "report", "-week")
+	require.Equal(t, exitOK, code)
+	assert.True(t, strings.HasPrefix(stdout, "day,task_id,project,hours,description\n"), stdout)
+	code, _, _ = runCLI(t, "report", "-week", "-from", "2026-10-01")
+	assert.Equal(t, exitUsage, code)
+}
+
+func TestStats(t *testing.T) {
+	isolate(t)
+
+	runCLI(t, "add", "-estimate", "3", "review budget")
+	runCLI(t, "add", "-estimate", "5", "write report")
+	runCLI(t, "add", "plan offsite")
+	code, _, _ := runCLI(t, "update", "3", "-estimate", "2.5")
+	require.Equal(t, exitOK, code)
+	code, _, _ = runCLI(t, "update", "3", "-estimate", "lots")
+	assert.Equal(t, exitUsage, code)
+	_, stdout, _ := runCLI(t, "show", "3")
+	assert.Contains(t, stdout, " / estimate: 2.5")
+	runCLI(t, "done", "1")
+
+	code, stdout, _ = runCLI(t, "stats")
+	require.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "Created: 3, completed: 1, completion rate: 33%\n")
+	assert.Contains(t, stdout, "Burndown, estimate left (open tasks):\n")
+	assert.True(t, strings.HasSuffix(stdout, " 7.5 (2)\n"), stdout)
+
+	code, stdout, _ = runCLI(t, "-o", "json", "stats", "-week")
+	require.Equal(t, exitOK, code)
+	var st types.Stats
+	require.NoError(t, json.Unmarshal([]byte(stdout), &st))
+	assert.Equal(t, 3, st.Created)
+	assert.Equal(t, 1, st.Completed)
+	assert.NotEmpty(t, st.Burndown)
+	_, stdout, _ = runCLI(t, "ls", "-q", "completed:today estimate>0")
+	assert.Contains(t, stdout, "review budget")
+
+	code, _, _ = runCLI(t, "stats", "-from", "2026-10-18", "-to", "2026-10-01")
+	assert.Equal(t, exitUsage, code)
+	_, stdout, _ = runCLI(t, "stats", "-from", "2020-01-01", "-to", "2020-01-31")
+	assert.Contains(t, stdout, "Created: 0, completed: 0, completion rate: 0%\nAverage lead time: -\nBurndown, open tasks:\n")
+}
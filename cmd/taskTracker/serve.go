@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"taskTracker/pkg/server"
+	"time"
+)
+
+// serveCommand runs the REST API until SIGINT or SIGTERM.
+func serveCommand(fs *flag.FlagSet) runFunc {
+	addrFlag := fs.String("addr", ":8080", "address to listen on")
+	return func(a *app, args []string) error {
+		if len(args) > 0 {
+			return usageErrorf("unexpected arguments %q", args)
+		}
+		srv := &http.Server{
+			Addr:              *addrFlag,
+			Handler:           server.New(a.store),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		errChan := make(chan error, 1)
+		go func() {
+			log.Println("Listening on", *addrFlag)
+			errChan <- srv.ListenAndServe()
+		}()
+
+		select {
+		case err := <-errChan:
+			return err
+		case <-ctx.Done():
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		log.Println("Server stopped")
+		return nil
+	}
+}
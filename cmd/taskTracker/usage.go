@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/utils"
+)
+
+// usageError is a mistake in the command line; it makes the command print its usage and exit with exitUsage.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+func usageErrorf(format string, a ...any) error {
+	return usageError(fmt.Sprintf(format, a...))
+}
+
+func newCommandFlagSet(cmd command, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: taskTracker %s", cmd.name)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprint(fs.Output(), " [flags]")
+		}
+		if cmd.args != "" {
+			fmt.Fprint(fs.Output(), " ", cmd.args)
+		}
+		fmt.Fprintf(fs.Output(), "\n\n%s\n", cmd.summary)
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nflags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+func mainUsage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "usage: taskTracker [global flags] <command> [flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	width := 0
+	for _, cmd := range commands {
+		width = max(width, len(cmd.name))
+	}
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nglobal flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(w, "\nRun 'taskTracker help <command>' for the flags of a command.")
+	fmt.Fprintf(w, "\nexit codes: %d ok, %d error, %d bad usage, %d task not found, %d storage busy, %d change not allowed\n",
+		exitOK, exitError, exitUsage, exitNotFound, exitBusy, exitConflict)
+}
+
+// help prints the usage of the command named in args, or the main usage.
+func help(fs *flag.FlagSet, args []string) int {
+	if len(args) == 0 {
+		fs.Usage()
+		return exitOK
+	}
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(fs.Output(), "unknown command %q\n\n", args[0])
+		fs.Usage()
+		return exitUsage
+	}
+	cfs := newCommandFlagSet(cmd, fs.Output())
+	cmd.setup(cfs)
+	cfs.Usage()
+	return exitOK
+}
+
+func formatNames() string {
+	names := make([]string, len(utils.Formats))
+	for i, f := range utils.Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, "|")
+}
+
+// parseInterspersed parses flags that may come before, between or after positional
+// arguments, so both "rm -h" and "update 3 -desc x" work.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	pos := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return pos, nil
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+}
+
+// parseIDs turns every argument into a task ID.
+func parseIDs(args []string) ([]int64, error) {
+	if len(args) == 0 {
+		return nil, usageError("provide at least one task id")
+	}
+	ids := make([]int64, 0, len(args))
+	for _, a := range args {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil || id < 1 {
+			return nil, usageErrorf("invalid task id %q", a)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseID expects exactly one task ID.
+func parseID(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, usageError("provide exactly one task id")
+	}
+	ids, err := parseIDs(args)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// joinArgs builds a description out of positional words.
+func joinArgs(args []string) string {
+	return strings.TrimSpace(strings.Join(args, " "))
+}
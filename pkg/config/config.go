@@ -0,0 +1,244 @@
+// Package config resolves where taskTracker keeps its data. Settings come from, first
+// match wins:
+//
+//	-store, -profile, -db                  the global flags
+//	TASKTRACKER_STORE, _PROFILE, _DB, _USER environment variables
+//	$XDG_CONFIG_HOME/taskTracker/config.json, or the file named by TASKTRACKER_CONFIG
+//
+// The storage root defaults to $XDG_DATA_HOME/taskTracker (~/.local/share/taskTracker),
+// so every directory uses the same tasks. Earlier versions used ./storage; while the
+// default root is empty and ./storage has tasks, Settings.Legacy points at it so the
+// caller can warn. A config file looks like
+//
+//	{
+//	  "profile": "work",
+//	  "store": "~/tasks",
+//	  "user": "alice",
+//	  "profiles": {
+//	    "work": {"store": "~/work/tasks"},
+//	    "personal": {"db": "personal.db"}
+//	  }
+//	}
+//
+// where store and db on the top level apply when no profile is selected. A profile
+// without a store gets <default root>/profiles/<name>. user names who creates and
+// changes tasks in the audit log and for assign; a profile may set its own. Relative paths in the file are
+// relative to the directory of the file, those of flags and variables to the working
+// directory.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	EnvConfig  = "TASKTRACKER_CONFIG"
+	EnvProfile = "TASKTRACKER_PROFILE"
+	EnvStore   = "TASKTRACKER_STORE"
+	EnvDB      = "TASKTRACKER_DB"
+	EnvUser    = "TASKTRACKER_USER"
+)
+
+const appDir = "taskTracker"
+
+// LegacyStore is the storage root, relative to the working directory, of the versions
+// before the config file.
+const LegacyStore = "storage"
+
+// Profile is a named storage.
+type Profile struct {
+	Store string `json:"store,omitempty"` // storage root of the JSON storage
+	DB    string `json:"db,omitempty"`    // embedded database used instead, see -db
+	User  string `json:"user,omitempty"`  // who creates and changes tasks, the OS user if empty
+}
+
+// File is the config file.
+type File struct {
+	Profile                     // used when no profile is selected
+	Default  string             `json:"profile,omitempty"` // profile selected by default
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Settings is what the flags, the environment and the config file add up to. Paths are
+// absolute.
+type Settings struct {
+	ConfigPath string `json:"config"`            // the config file, it does not have to exist
+	Profile    string `json:"profile,omitempty"` // "" is the default storage
+	Store      string `json:"store"`
+	DB         string `json:"db,omitempty"`
+	User       string `json:"user,omitempty"` // "" is the OS user
+	// Legacy is set to ./storage when it holds tasks while the default storage, which is
+	// in use because nothing else is configured, is still empty.
+	Legacy string `json:"legacy,omitempty"`
+}
+
+// Path returns the config file: TASKTRACKER_CONFIG or config.json in the user config
+// directory.
+func Path() (string, error) {
+	if p := os.Getenv(EnvConfig); p != "" {
+		return filepath.Abs(expandHome(p))
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appDir, "config.json"), nil
+}
+
+// DefaultStore returns the storage root used when nothing else is configured.
+func DefaultStore() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", appDir), nil
+}
+
+// Load reads the config file at path. A missing file is an empty config. Relative paths
+// of the file are made absolute.
+func Load(path string) (*File, error) {
+	f := &File{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	f.Profile = f.Profile.resolve(dir)
+	for name, p := range f.Profiles {
+		f.Profiles[name] = p.resolve(dir)
+	}
+	return f, nil
+}
+
+// Resolve selects the profile and the storage. flags holds -store and -db and profile
+// -profile, "" where they are not given.
+func Resolve(flags Profile, profile string) (*Settings, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	file, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Settings{ConfigPath: path, Profile: first(profile, os.Getenv(EnvProfile), file.Default)}
+
+	base := file.Profile
+	if s.Profile != "" {
+		p, ok := file.Profiles[s.Profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q, %s", s.Profile, file.known(path))
+		}
+		base = p
+	}
+	if s.Store, err = abs(first(flags.Store, os.Getenv(EnvStore))); err != nil {
+		return nil, err
+	}
+	if s.Store == "" {
+		s.Store = base.Store
+	}
+	if s.Store == "" {
+		if s.Store, err = DefaultStore(); err != nil {
+			return nil, err
+		}
+		if s.Profile != "" {
+			s.Store = filepath.Join(s.Store, "profiles", s.Profile)
+		} else {
+			s.Legacy = legacyStore(s.Store)
+		}
+	}
+	if s.DB, err = abs(first(flags.DB, os.Getenv(EnvDB))); err != nil {
+		return nil, err
+	}
+	if s.DB == "" {
+		s.DB = base.DB
+	}
+	s.User = first(os.Getenv(EnvUser), base.User, file.User)
+	return s, nil
+}
+
+// legacyStore returns the absolute LegacyStore when it has tasks and store has none.
+func legacyStore(store string) string {
+	if hasTasks(store) {
+		return ""
+	}
+	legacy, err := filepath.Abs(LegacyStore)
+	if err != nil || !hasTasks(legacy) {
+		return ""
+	}
+	return legacy
+}
+
+// hasTasks reports whether root is a storage that tasks were ever created in.
+func hasTasks(root string) bool {
+	_, err := os.Stat(filepath.Join(root, "lastID.json"))
+	return err == nil
+}
+
+// known lists the profiles of the file for an error message.
+func (f *File) known(path string) string {
+	if len(f.Profiles) == 0 {
+		return "no profiles are defined in " + path
+	}
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "expected one of " + strings.Join(names, ", ")
+}
+
+func (p Profile) resolve(dir string) Profile {
+	return Profile{Store: relativeTo(dir, p.Store), DB: relativeTo(dir, p.DB), User: p.User}
+}
+
+func relativeTo(dir, path string) string {
+	path = expandHome(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func abs(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	return filepath.Abs(expandHome(path))
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~"+string(filepath.Separator)) && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	home := filepath.Join(dir, "home")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	for _, env := range []string{EnvConfig, EnvProfile, EnvStore, EnvDB, EnvUser} {
+		t.Setenv(env, "")
+	}
+	defaultStore := filepath.Join(dir, "data", "taskTracker")
+
+	t.Run("defaults without a config file", func(t *testing.T) {
+		s, err := Resolve(Profile{}, "")
+		require.NoError(t, err)
+		assert.Equal(t, &Settings{ConfigPath: filepath.Join(dir, "config", "taskTracker", "config.json"), Store: defaultStore}, s)
+	})
+
+	t.Run("./storage of earlier versions", func(t *testing.T) {
+		legacy := filepath.Join(dir, LegacyStore)
+		require.NoError(t, os.MkdirAll(legacy, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(legacy, "lastID.json"), []byte("3"), 0644))
+		t.Cleanup(func() { os.RemoveAll(legacy) })
+		s, err := Resolve(Profile{}, "")
+		require.NoError(t, err)
+		assert.Equal(t, legacy, s.Legacy)
+		s, err = Resolve(Profile{Store: "elsewhere"}, "")
+		require.NoError(t, err)
+		assert.Empty(t, s.Legacy, "only when nothing is configured")
+
+		require.NoError(t, os.MkdirAll(defaultStore, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(defaultStore, "lastID.json"), []byte("1"), 0644))
+		t.Cleanup(func() { os.RemoveAll(defaultStore) })
+		s, err = Resolve(Profile{}, "")
+		require.NoError(t, err)
+		assert.Empty(t, s.Legacy, "not once the default storage has tasks")
+	})
+
+	cfg := filepath.Join(dir, "my.json")
+	t.Setenv(EnvConfig, cfg)
+	require.NoError(t, os.WriteFile(cfg, []byte(`{
+		"store": "~/tasks",
+		"db": "all.db",
+		"user": "alice",
+		"profiles": {
+			"work": {"store": "/srv/work", "db": "work.db", "user": "alice.smith"},
+			"personal": {}
+		}
+	}`), 0644))
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		flags   Profile
+		profile string
+		want    Settings
+	}{
+		{name: "top level of the file", want: Settings{Store: filepath.Join(home, "tasks"), DB: filepath.Join(dir, "all.db"), User: "alice"}},
+		{name: "profile flag", profile: "work", want: Settings{Profile: "work", Store: "/srv/work", DB: filepath.Join(dir, "work.db"), User: "alice.smith"}},
+		{
+			name: "profile variable",
+			env:  map[string]string{EnvProfile: "personal"},
+			want: Settings{Profile: "personal", Store: filepath.Join(defaultStore, "profiles", "personal"), User: "alice"},
+		},
+		{
+			name:    "flag beats variable",
+			env:     map[string]string{EnvProfile: "personal", EnvStore: "env", EnvDB: "env.db", EnvUser: "bob"},
+			flags:   Profile{Store: "flag"},
+			profile: "work",
+			want:    Settings{Profile: "work", Store: filepath.Join(dir, "flag"), DB: filepath.Join(dir, "env.db"), User: "bob"},
+		},
+		{name: "variable beats file", env: map[string]string{EnvStore: "/tmp/env"}, want: Settings{Store: "/tmp/env", DB: filepath.Join(dir, "all.db"), User: "alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			s, err := Resolve(tt.flags, tt.profile)
+			require.NoError(t, err)
+			tt.want.ConfigPath = cfg
+			assert.Equal(t, &tt.want, s)
+		})
+	}
+
+	t.Run("unknown profile", func(t *testing.T) {
+		_, err := Resolve(Profile{}, "home")
+		assert.ErrorContains(t, err, `unknown profile "home", expected one of personal, work`)
+	})
+
+	t.Run("broken file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(cfg, []byte(`{"store": `), 0644))
+		_, err := Resolve(Profile{}, "")
+		assert.ErrorContains(t, err, "config "+cfg)
+	})
+}
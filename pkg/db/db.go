@@ -0,0 +1,339 @@
+// Package db is a small embedded single-file key/value store.
+//
+// Every write is appended to the file as one checksummed record and synced before it
+// is acknowledged, so existing data is never rewritten in place. A crash can only
+// lose the record that was being written; a torn record at the end of the file is
+// dropped the next time the file is opened. A damaged record followed by others was not
+// torn by a crash, Open fails with ErrCorrupt instead of dropping the records after it.
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	opPut byte = iota + 1
+	opDelete
+)
+
+var (
+	ErrClosed  = errors.New("db: database is closed")
+	ErrCorrupt = errors.New("db: corrupt record")
+)
+
+// record header: payload length and CRC32 of the payload
+const headerSize = 8
+
+// Op is a single change inside a Batch.
+type Op struct {
+	Delete bool
+	Key    string
+	Value  []byte
+}
+
+type DB struct {
+	mu      sync.RWMutex
+	path    string
+	f       *os.File
+	data    map[string][]byte
+	garbage int   // number of records overwritten or deleted since the last compaction
+	end     int64 // offset right after the last complete record, where the next one goes
+}
+
+// Open opens the database file at path, creating it when it does not exist.
+func Open(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{path: path, f: f, data: make(map[string][]byte)}
+	end, err := db.load()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// drop a torn tail left by a crash so new records start at a clean offset
+	if err := f.Truncate(end); err != nil {
+		f.Close()
+		return nil, err
+	}
+	db.end = end
+	return db, nil
+}
+
+// load replays the file and returns the offset right after the last complete record.
+// Only the last record may be torn: a bad checksum with more data behind it is ErrCorrupt,
+// and so is a length running past the end of the file when complete records follow.
+func (db *DB) load() (int64, error) {
+	info, err := db.f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(db.f)
+	var off int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return off, nil // EOF or a torn header
+		}
+		size := binary.LittleEndian.Uint32(header[:4])
+		sum := binary.LittleEndian.Uint32(header[4:])
+		if rest := info.Size() - off - headerSize; int64(size) > rest {
+			tail, err := io.ReadAll(r)
+			if err != nil {
+				return off, err
+			}
+			if holdsRecord(tail) {
+				return off, fmt.Errorf("%w at offset %d of %s: length %d runs past the end", ErrCorrupt, off, db.path, size)
+			}
+			return off, nil
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return off, nil
+		}
+		if crc32.ChecksumIEEE(payload) != sum {
+			if _, err := r.Peek(1); err == io.EOF {
+				return off, nil
+			}
+			return off, fmt.Errorf("%w at offset %d of %s", ErrCorrupt, off, db.path)
+		}
+		ops, err := decodeOps(payload)
+		if err != nil {
+			return off, err
+		}
+		db.apply(ops)
+		off += headerSize + int64(size)
+	}
+}
+
+// holdsRecord reports whether a complete record starts anywhere in b, which a record
+// torn by a crash, the last one written, can not be followed by.
+func holdsRecord(b []byte) bool {
+	for i := 0; i+headerSize < len(b); i++ {
+		size := int(binary.LittleEndian.Uint32(b[i:]))
+		payload := b[i+headerSize:]
+		if size == 0 || size > len(payload) {
+			continue
+		}
+		payload = payload[:size]
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(b[i+4:]) {
+			continue
+		}
+		if _, err := decodeOps(payload); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *DB) apply(ops []Op) {
+	for _, op := range ops {
+		if _, ok := db.data[op.Key]; ok {
+			db.garbage++
+		}
+		if op.Delete {
+			delete(db.data, op.Key)
+			continue
+		}
+		db.data[op.Key] = op.Value
+	}
+}
+
+// Get returns a copy of the value stored under key.
+func (db *DB) Get(key string) ([]byte, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	v, ok := db.data[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), v...), true
+}
+
+// Keys returns all keys starting with prefix in lexical order.
+func (db *DB) Keys(prefix string) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	keys := make([]string, 0)
+	for k := range db.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (db *DB) Put(key string, value []byte) error {
+	return db.Batch([]Op{{Key: key, Value: value}})
+}
+
+func (db *DB) Delete(key string) error {
+	return db.Batch([]Op{{Delete: true, Key: key}})
+}
+
+// Batch writes all ops as one record: after a crash either all of them are visible or none.
+// A record that fails to be written or synced is cut off again, and the next one is
+// written at the same offset, so a failed write does not leave a damaged record behind
+// the ones that follow.
+func (db *DB) Batch(ops []Op) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.f == nil {
+		return ErrClosed
+	}
+	rec := encodeRecord(ops)
+	_, err := db.f.WriteAt(rec, db.end)
+	if err == nil {
+		err = db.f.Sync()
+	}
+	if err != nil {
+		db.f.Truncate(db.end)
+		return err
+	}
+	db.end += int64(len(rec))
+	db.apply(ops)
+	return nil
+}
+
+// Compact rewrites the file with only the live keys. The new file replaces the old one by rename.
+func (db *DB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.f == nil {
+		return ErrClosed
+	}
+	tmp := db.path + ".compact"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(db.data))
+	for k := range db.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w := bufio.NewWriter(f)
+	var end int64
+	for _, k := range keys {
+		n, err := w.Write(encodeRecord([]Op{{Key: k, Value: db.data[k]}}))
+		if err != nil {
+			f.Close()
+			return err
+		}
+		end += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, db.path); err != nil {
+		f.Close()
+		return err
+	}
+	db.f.Close()
+	db.f = f
+	db.end = end
+	db.garbage = 0
+	return nil
+}
+
+// Garbage reports how many records Compact would drop.
+func (db *DB) Garbage() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.garbage
+}
+
+// Len reports the number of live keys.
+func (db *DB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return len(db.data)
+}
+
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.f == nil {
+		return ErrClosed
+	}
+	err := db.f.Close()
+	db.f = nil
+	return err
+}
+
+func encodeRecord(ops []Op) []byte {
+	payload := make([]byte, 0, 64)
+	for _, op := range ops {
+		kind := opPut
+		if op.Delete {
+			kind = opDelete
+		}
+		payload = append(payload, kind)
+		payload = binary.AppendUvarint(payload, uint64(len(op.Key)))
+		payload = append(payload, op.Key...)
+		if !op.Delete {
+			payload = binary.AppendUvarint(payload, uint64(len(op.Value)))
+			payload = append(payload, op.Value...)
+		}
+	}
+	rec := make([]byte, headerSize, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(rec[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(rec[4:], crc32.ChecksumIEEE(payload))
+	return append(rec, payload...)
+}
+
+func decodeOps(payload []byte) ([]Op, error) {
+	ops := make([]Op, 0, 1)
+	for len(payload) > 0 {
+		kind := payload[0]
+		payload = payload[1:]
+		key, rest, err := readChunk(payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = rest
+		switch kind {
+		case opPut:
+			val, rest, err := readChunk(payload)
+			if err != nil {
+				return nil, err
+			}
+			payload = rest
+			ops = append(ops, Op{Key: string(key), Value: val})
+		case opDelete:
+			ops = append(ops, Op{Delete: true, Key: string(key)})
+		default:
+			return nil, ErrCorrupt
+		}
+	}
+	return ops, nil
+}
+
+func readChunk(b []byte) ([]byte, []byte, error) {
+	n, size := binary.Uvarint(b)
+	if size <= 0 || uint64(len(b)-size) < n {
+		return nil, nil, ErrCorrupt
+	}
+	b = b[size:]
+	return append([]byte(nil), b[:n]...), b[n:], nil
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	t.Run("creates missing file and directories", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "tasks.db")
+		d, err := Open(path)
+		require.NoError(t, err)
+		defer d.Close()
+		_, err = os.Stat(path)
+		assert.NoError(t, err)
+	})
+
+	t.Run("data survives reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		d, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, d.Put("a", []byte("1")))
+		require.NoError(t, d.Put("b", []byte("2")))
+		require.NoError(t, d.Put("a", []byte("3")))
+		require.NoError(t, d.Delete("b"))
+		require.NoError(t, d.Close())
+
+		d, err = Open(path)
+		require.NoError(t, err)
+		defer d.Close()
+		v, ok := d.Get("a")
+		require.True(t, ok)
+		assert.Equal(t, "3", string(v))
+		_, ok = d.Get("b")
+		assert.False(t, ok)
+		assert.Equal(t, 2, d.Garbage())
+	})
+
+	t.Run("torn tail is dropped", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		d, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, d.Put("a", []byte("1")))
+		require.NoError(t, d.Close())
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		good := info.Size()
+
+		// simulate a crash in the middle of the second record
+		rec := encodeRecord([]Op{{Key: "b", Value: []byte("2")}})
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = f.Write(rec[:len(rec)-1])
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		d, err = Open(path)
+		require.NoError(t, err)
+		_, ok := d.Get("b")
+		assert.False(t, ok)
+		require.NoError(t, d.Put("c", []byte("3")))
+		require.NoError(t, d.Close())
+
+		d, err = Open(path)
+		require.NoError(t, err)
+		defer d.Close()
+		v, ok := d.Get("c")
+		require.True(t, ok)
+		assert.Equal(t, "3", string(v))
+		info, err = os.Stat(path)
+		require.NoError(t, err)
+		assert.Greater(t, info.Size(), good)
+	})
+
+	t.Run("damaged record in the middle -> ErrCorrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		d, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, d.Put("a", []byte("1")))
+		require.NoError(t, d.Put("b", []byte("2")))
+		require.NoError(t, d.Put("c", []byte("3")))
+		require.NoError(t, d.Close())
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		size := len(data)
+
+		// flip a byte of the payload of the second record
+		first := len(encodeRecord([]Op{{Key: "a", Value: []byte("1")}}))
+		data[first+headerSize] ^= 0xff
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		_, err = Open(path)
+		require.ErrorIs(t, err, ErrCorrupt)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(size), info.Size(), "nothing is truncated")
+	})
+
+	t.Run("length running past the end in the middle -> ErrCorrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		d, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, d.Put("a", []byte("1")))
+		require.NoError(t, d.Put("b", []byte("2")))
+		require.NoError(t, d.Put("c", []byte("3")))
+		require.NoError(t, d.Close())
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		first := len(encodeRecord([]Op{{Key: "a", Value: []byte("1")}}))
+		data[first+3] = 0x7f // length of the second record
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		_, err = Open(path)
+		require.ErrorIs(t, err, ErrCorrupt)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(data)), info.Size(), "nothing is truncated")
+	})
+}
+
+func TestBatchAfterFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	d, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, d.Put("a", []byte("1")))
+
+	// what a write that failed halfway, and could not be cut off, leaves behind
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	rec := encodeRecord([]Op{{Key: "lost", Value: []byte("a long value that was never acknowledged")}})
+	_, err = f.Write(rec[:len(rec)/2])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.NoError(t, d.Put("b", []byte("2")))
+	require.NoError(t, d.Put("c", []byte("3")))
+	require.NoError(t, d.Close())
+
+	d, err = Open(path)
+	require.NoError(t, err, "no damaged record before b and c")
+	defer d.Close()
+	assert.Equal(t, []string{"a", "b", "c"}, d.Keys(""))
+}
+
+func TestBatch(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	defer d.Close()
+
+	require.NoError(t, d.Batch([]Op{{Key: "task/1", Value: []byte("x")}, {Key: "meta/last", Value: []byte("1")}}))
+	assert.Equal(t, []string{"task/1"}, d.Keys("task/"))
+	assert.Equal(t, 2, d.Len())
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	d, err := Open(path)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, d.Put("k", []byte{byte(i)}))
+	}
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Compact())
+	assert.Equal(t, 0, d.Garbage())
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size())
+
+	require.NoError(t, d.Put("j", []byte("after")))
+	require.NoError(t, d.Close())
+
+	d, err = Open(path)
+	require.NoError(t, err)
+	defer d.Close()
+	v, _ := d.Get("k")
+	assert.Equal(t, []byte{99}, v)
+	v, _ = d.Get("j")
+	assert.Equal(t, "after", string(v))
+}
+
+func TestClosed(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+	require.ErrorIs(t, d.Put("a", nil), ErrClosed)
+}
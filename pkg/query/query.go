@@ -0,0 +1,422 @@
+// Package query compiles filter expressions like
+//
+//	done:false desc~deploy created>=2026-10-01 updated<7d
+//
+// into predicates over types.Task. Terms are separated by spaces and all of them
+// have to match. A term is <field><op><value>; values with spaces are quoted:
+// desc~"release notes".
+//
+// Operators: ":" or "=" equal, "!=" not equal, "~" contains, "!~" does not contain
+// (case-insensitive, text fields only), "<", "<=", ">", ">=".
+//
+// Time fields take a date (2026-10-01), today, yesterday, a weekday (monday is the
+// most recent Monday, today included) or an age like 7d, 12h, 2w. An age compares
+// how long ago something happened: updated<7d means updated less than 7 days ago.
+// For due dates the age points into the future instead: due<3d is due within 3 days.
+// due:none and due!=none test whether a due date is set at all; a task without one
+// never matches a comparison.
+//
+// priority compares by rank (priority>=high), tag:x matches tasks tagged x and
+// tag!=x the ones that are not. status takes todo, in_progress, blocked, done or
+// cancelled; done:true is the same as status:done. parent:3 matches the subtasks of
+// task 3, parent:0 the tasks that are not a subtask. project:billing matches the tasks
+// of a project, project:"" the ones without; assignee and creator work the same way.
+// estimate compares numbers (estimate>=3, estimate:0 for tasks without one) and
+// completed is when a task was marked done.
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+	"unicode"
+)
+
+// Predicate reports whether a task matches a query.
+type Predicate func(t *types.Task) bool
+
+type kind int
+
+const (
+	kindInt kind = iota
+	kindBool
+	kindText
+	kindTime
+	kindPriority
+	kindTags
+	kindStatus
+	kindNumber
+)
+
+// field is a queryable and sortable property of a task.
+type field struct {
+	kind kind
+	get  func(t *types.Task) any
+	// future marks times that usually lie ahead, so ages count forward from now
+	future bool
+}
+
+var fields = map[string]field{
+	"id":        {kind: kindInt, get: func(t *types.Task) any { return t.ID }},
+	"parent":    {kind: kindInt, get: func(t *types.Task) any { return t.ParentID }},
+	"done":      {kind: kindBool, get: func(t *types.Task) any { return t.Done() }},
+	"status":    {kind: kindStatus, get: func(t *types.Task) any { return t.Status }},
+	"desc":      {kind: kindText, get: func(t *types.Task) any { return t.Description }},
+	"created":   {kind: kindTime, get: func(t *types.Task) any { return t.CreatedAt }},
+	"updated":   {kind: kindTime, get: func(t *types.Task) any { return t.UpdateAt }},
+	"due":       {kind: kindTime, get: func(t *types.Task) any { return t.DueAt }, future: true},
+	"priority":  {kind: kindPriority, get: func(t *types.Task) any { return t.Priority.OrDefault() }},
+	"tag":       {kind: kindTags, get: func(t *types.Task) any { return t.Tags }},
+	"project":   {kind: kindText, get: func(t *types.Task) any { return t.Project }},
+	"assignee":  {kind: kindText, get: func(t *types.Task) any { return t.Assignee }},
+	"creator":   {kind: kindText, get: func(t *types.Task) any { return t.CreatedBy }},
+	"estimate":  {kind: kindNumber, get: func(t *types.Task) any { return t.Estimate }},
+	"completed": {kind: kindTime, get: func(t *types.Task) any { return t.CompletedAt() }},
+}
+
+// aliases map alternative spellings, e.g. the json tags, to field names.
+var aliases = map[string]string{
+	"description": "desc",
+	"created_at":  "created",
+	"updated_at":  "updated",
+	"due_at":      "due",
+	"tags":        "tag",
+	"parent_id":   "parent",
+	"created_by":  "creator",
+	"done_at":     "completed",
+}
+
+func lookupField(name string) (string, field, bool) {
+	if a, ok := aliases[name]; ok {
+		name = a
+	}
+	f, ok := fields[name]
+	return name, f, ok
+}
+
+// operators, longest first so "<=" wins over "<"
+var operators = []string{"!=", "!~", "<=", ">=", ":", "=", "~", "<", ">"}
+
+// Parse compiles expr. Relative times are resolved against now.
+// An empty expression matches every task.
+func Parse(expr string, now time.Time) (Predicate, error) {
+	terms, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	preds := make([]Predicate, 0, len(terms))
+	for _, term := range terms {
+		p, err := parseTerm(term, now)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, p)
+	}
+	return func(t *types.Task) bool {
+		for _, p := range preds {
+			if !p(t) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// tokenize splits expr at spaces outside of double quotes and drops the quotes.
+func tokenize(expr string) ([]string, error) {
+	terms := make([]string, 0)
+	var cur strings.Builder
+	inQuote, started := false, false
+	for _, r := range expr {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			started = true
+		case unicode.IsSpace(r) && !inQuote:
+			if started {
+				terms = append(terms, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("query: unterminated quote in %q", expr)
+	}
+	if started {
+		terms = append(terms, cur.String())
+	}
+	return terms, nil
+}
+
+func parseTerm(term string, now time.Time) (Predicate, error) {
+	end := strings.IndexFunc(term, func(r rune) bool { return !(unicode.IsLetter(r) || r == '_') })
+	if end <= 0 {
+		return nil, fmt.Errorf("query: %q: expected <field><op><value>", term)
+	}
+	name, rest := strings.ToLower(term[:end]), term[end:]
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(rest, o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("query: %q: expected one of %s after %q", term, strings.Join(operators, " "), name)
+	}
+	if op == "=" {
+		op = ":"
+	}
+	value := rest[len(op):]
+
+	name, f, ok := lookupField(name)
+	if !ok {
+		return nil, fmt.Errorf("query: unknown field %q", name)
+	}
+	p, err := compile(f, op, value, now)
+	if err != nil {
+		return nil, fmt.Errorf("query: %q: %w", term, err)
+	}
+	return p, nil
+}
+
+func compile(f field, op, value string, now time.Time) (Predicate, error) {
+	switch f.kind {
+	case kindInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		cmp, err := ordered(op)
+		if err != nil {
+			return nil, err
+		}
+		return func(t *types.Task) bool { return cmp(compareInt(f.get(t).(int64), n)) }, nil
+	case kindNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		cmp, err := ordered(op)
+		if err != nil {
+			return nil, err
+		}
+		return func(t *types.Task) bool { return cmp(compareFloat(f.get(t).(float64), n)) }, nil
+	case kindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		if op != ":" && op != "!=" {
+			return nil, fmt.Errorf("operator %s does not work on true/false", op)
+		}
+		return func(t *types.Task) bool { return (f.get(t).(bool) == b) == (op == ":") }, nil
+	case kindText:
+		return compileText(f, op, value)
+	case kindTime:
+		return compileTime(f, op, value, now)
+	case kindPriority:
+		p, err := types.ParsePriority(value)
+		if err != nil {
+			return nil, err
+		}
+		cmp, err := ordered(op)
+		if err != nil {
+			return nil, err
+		}
+		return func(t *types.Task) bool {
+			return cmp(compareInt(int64(f.get(t).(types.Priority).Rank()), int64(p.Rank())))
+		}, nil
+	case kindStatus:
+		st, err := types.ParseStatus(strings.ToLower(value))
+		if err != nil {
+			return nil, err
+		}
+		if op != ":" && op != "!=" {
+			return nil, fmt.Errorf("operator %s does not work on status", op)
+		}
+		return func(t *types.Task) bool { return (f.get(t).(types.Status) == st) == (op == ":") }, nil
+	case kindTags:
+		if op != ":" && op != "!=" {
+			return nil, fmt.Errorf("operator %s does not work on tags", op)
+		}
+		tag := strings.ToLower(value)
+		return func(t *types.Task) bool {
+			return slices.Contains(f.get(t).([]string), tag) == (op == ":")
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported field")
+}
+
+func compileText(f field, op, value string) (Predicate, error) {
+	needle := strings.ToLower(value)
+	switch op {
+	case "~", "!~":
+		return func(t *types.Task) bool {
+			return strings.Contains(strings.ToLower(f.get(t).(string)), needle) == (op == "~")
+		}, nil
+	case ":", "!=":
+		return func(t *types.Task) bool {
+			return strings.EqualFold(f.get(t).(string), value) == (op == ":")
+		}, nil
+	}
+	cmp, err := ordered(op)
+	if err != nil {
+		return nil, err
+	}
+	return func(t *types.Task) bool {
+		return cmp(strings.Compare(strings.ToLower(f.get(t).(string)), needle))
+	}, nil
+}
+
+// compileTime compares against whole days for dates and against an instant for ages.
+func compileTime(f field, op, value string, now time.Time) (Predicate, error) {
+	if strings.EqualFold(value, "none") {
+		if op != ":" && op != "!=" {
+			return nil, fmt.Errorf("operator %s does not work with none", op)
+		}
+		return func(t *types.Task) bool { return f.get(t).(time.Time).IsZero() == (op == ":") }, nil
+	}
+	get := func(t *types.Task) (time.Time, bool) {
+		ts := f.get(t).(time.Time)
+		return ts, !ts.IsZero()
+	}
+
+	if age, ok := ParseAge(value); ok {
+		if f.future {
+			// "due in less than 3 days" is "before now+3d"
+			at := now.Add(age)
+			cmp, err := ordered(op)
+			if err != nil {
+				return nil, err
+			}
+			return func(t *types.Task) bool {
+				ts, ok := get(t)
+				return ok && cmp(ts.Compare(at))
+			}, nil
+		}
+		// older means earlier: "less than 7 days ago" is "after now-7d"
+		at := now.Add(-age)
+		flipped := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", ":": ":", "!=": "!="}
+		cmp, err := ordered(flipped[op])
+		if err != nil {
+			return nil, err
+		}
+		return func(t *types.Task) bool {
+			ts, ok := get(t)
+			return ok && cmp(ts.Compare(at))
+		}, nil
+	}
+
+	day, err := parseDay(value, now)
+	if err != nil {
+		return nil, err
+	}
+	next := day.AddDate(0, 0, 1)
+	var p func(ts time.Time) bool
+	switch op {
+	case ":":
+		p = func(ts time.Time) bool { return !ts.Before(day) && ts.Before(next) }
+	case "!=":
+		p = func(ts time.Time) bool { return ts.Before(day) || !ts.Before(next) }
+	case "<":
+		p = func(ts time.Time) bool { return ts.Before(day) }
+	case "<=":
+		p = func(ts time.Time) bool { return ts.Before(next) }
+	case ">":
+		p = func(ts time.Time) bool { return !ts.Before(next) }
+	case ">=":
+		p = func(ts time.Time) bool { return !ts.Before(day) }
+	default:
+		return nil, fmt.Errorf("operator %s does not work on times", op)
+	}
+	return func(t *types.Task) bool {
+		ts, ok := get(t)
+		return ok && p(ts)
+	}, nil
+}
+
+// ParseAge parses 30m, 12h, 7d and 2w.
+func ParseAge(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	unit := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[s[len(s)-1]]
+	if unit == 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// parseDay returns the start of the day named by s.
+func parseDay(s string, now time.Time) (time.Time, error) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(s) {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(s, wd.String()) {
+			back := (int(today.Weekday()) - int(wd) + 7) % 7
+			return today.AddDate(0, 0, -back), nil
+		}
+	}
+	day, err := time.ParseInLocation(types.DateLayout, s, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (%s), today, yesterday, a weekday or an age like 7d", s, types.DateLayout)
+	}
+	return day, nil
+}
+
+// ordered turns an operator into a test on the result of a three-way comparison.
+func ordered(op string) (func(c int) bool, error) {
+	switch op {
+	case ":":
+		return func(c int) bool { return c == 0 }, nil
+	case "!=":
+		return func(c int) bool { return c != 0 }, nil
+	case "<":
+		return func(c int) bool { return c < 0 }, nil
+	case "<=":
+		return func(c int) bool { return c <= 0 }, nil
+	case ">":
+		return func(c int) bool { return c > 0 }, nil
+	case ">=":
+		return func(c int) bool { return c >= 0 }, nil
+	}
+	return nil, fmt.Errorf("operator %s only works on text", op)
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package query
+
+import (
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// now is a Thursday
+var now = time.Date(2026, time.October, 15, 12, 0, 0, 0, time.Local)
+
+func sample() []*types.Task {
+	return []*types.Task{
+		{ID: 1, Description: "Deploy billing", CreatedAt: now.AddDate(0, 0, -20), UpdateAt: now.AddDate(0, 0, -10), Estimate: 3},
+		{
+			ID: 2, Description: "write docs", Status: types.StatusDone, CreatedAt: now.AddDate(0, 0, -5), UpdateAt: now.AddDate(0, 0, -1),
+			DoneAt: now.AddDate(0, 0, -2),
+		},
+		{
+			ID: 3, Description: "deploy search", CreatedAt: now.AddDate(0, 0, -3), UpdateAt: now.Add(-time.Hour),
+			Priority: types.PriorityUrgent, DueAt: now.AddDate(0, 0, 2), Tags: []string{"ops", "search"},
+			Project: "search", Estimate: 5.5,
+		},
+		{ID: 4, Description: "plan", CreatedAt: now, UpdateAt: now, Priority: types.PriorityLow, DueAt: now.AddDate(0, 0, -1), Tags: []string{"ops"}},
+	}
+}
+
+func match(t *testing.T, expr string) []int64 {
+	t.Helper()
+	p, err := Parse(expr, now)
+	require.NoError(t, err)
+	ids := make([]int64, 0)
+	for _, elem := range sample() {
+		if p(elem) {
+			ids = append(ids, elem.ID)
+		}
+	}
+	return ids
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		expr string
+		want []int64
+	}{
+		{"", []int64{1, 2, 3, 4}},
+		{"done:false", []int64{1, 3, 4}},
+		{"done=true", []int64{2}},
+		{"desc~DEPLOY", []int64{1, 3}},
+		{"desc!~deploy", []int64{2, 4}},
+		{`desc:"write docs"`, []int64{2}},
+		{`desc~"deploy s"`, []int64{3}},
+		{"id>=2 id!=3", []int64{2, 4}},
+		{"created>=2026-10-01", []int64{2, 3, 4}},
+		{"created<2026-10-10", []int64{1}},
+		{"created:2026-10-10", []int64{2}},
+		{"created<=2026-10-10", []int64{1, 2}},
+		{"created>2026-10-10", []int64{3, 4}},
+		{"updated<7d", []int64{2, 3, 4}},
+		{"updated>7d", []int64{1}},
+		{"updated<2h", []int64{3, 4}},
+		{"updated>=monday", []int64{2, 3, 4}},
+		{"updated>=today", []int64{3, 4}},
+		{"updated:yesterday", []int64{2}},
+		{"created_at>=2026-10-01 description~docs", []int64{2}},
+		{"done:false desc~deploy created>=2026-10-01 updated<7d", []int64{3}},
+		{"priority:normal", []int64{1, 2}},
+		{"priority>=high", []int64{3}},
+		{"priority<normal", []int64{4}},
+		{"tag:OPS", []int64{3, 4}},
+		{"tags!=search", []int64{1, 2, 4}},
+		{"project:Search", []int64{3}},
+		{`project:""`, []int64{1, 2, 4}},
+		{"due:none", []int64{1, 2}},
+		{"due!=none", []int64{3, 4}},
+		{"due<today", []int64{4}},
+		{"due<3d", []int64{3, 4}},
+		{"due>1d", []int64{3}},
+		{"estimate>=3", []int64{1, 3}},
+		{"estimate:0", []int64{2, 4}},
+		{"estimate<5.5 estimate>0", []int64{1}},
+		{"completed<3d", []int64{2}},
+		{"done_at:none", []int64{1, 3, 4}},
+		{"completed:yesterday", []int64{}},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			assert.Equal(t, c.want, match(t, c.expr))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"foo:bar",
+		"done",
+		"done:maybe",
+		"done>true",
+		"id:abc",
+		"id~1",
+		"created>last-week",
+		`desc~"open`,
+		":x",
+		"priority:asap",
+		"tag~op",
+		"due>none",
+		"estimate:lots",
+		"estimate~3",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := Parse(expr, now)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	ids := func(arr []*types.Task) []int64 {
+		res := make([]int64, 0, len(arr))
+		for _, elem := range arr {
+			res = append(res, elem.ID)
+		}
+		return res
+	}
+
+	cmp, err := ParseSort("-created")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4, 3, 2, 1}, ids(Run(sample(), nil, cmp, 0, 0)))
+
+	cmp, err = ParseSort("done, desc")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3, 4, 2}, ids(Run(sample(), nil, cmp, 0, 0)))
+
+	cmp, err = ParseSort("-priority,id")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 1, 2, 4}, ids(Run(sample(), nil, cmp, 0, 0)))
+
+	cmp, err = ParseSort("due")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4, 3, 1, 2}, ids(Run(sample(), nil, cmp, 0, 0)))
+
+	cmp, err = ParseSort("-estimate,id")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 1, 2, 4}, ids(Run(sample(), nil, cmp, 0, 0)))
+
+	_, err = ParseSort("size")
+	assert.Error(t, err)
+	_, err = ParseSort(",")
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	p, err := Parse("desc~deploy", now)
+	require.NoError(t, err)
+	cmp, err := ParseSort("-id")
+	require.NoError(t, err)
+
+	res := Run(sample(), p, cmp, 0, 1)
+	require.Len(t, res, 1)
+	assert.Equal(t, int64(3), res[0].ID)
+
+	res = Run(sample(), p, cmp, 1, 5)
+	require.Len(t, res, 1)
+	assert.Equal(t, int64(1), res[0].ID)
+
+	assert.Empty(t, Run(sample(), p, cmp, 10, 0))
+}
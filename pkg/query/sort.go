@@ -0,0 +1,107 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// Compare orders two tasks, like the functions passed to slices.SortFunc.
+type Compare func(a, b *types.Task) int
+
+// ParseSort compiles a comma separated list of fields, e.g. "created,-id".
+// A leading "-" sorts that field in descending order.
+func ParseSort(spec string) (Compare, error) {
+	keys := make([]Compare, 0)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := strings.HasPrefix(part, "-")
+		name, f, ok := lookupField(strings.ToLower(strings.TrimPrefix(part, "-")))
+		if !ok {
+			return nil, fmt.Errorf("sort: unknown field %q", name)
+		}
+		cmp := compareField(f)
+		if desc {
+			asc := cmp
+			cmp = func(a, b *types.Task) int { return -asc(a, b) }
+		}
+		keys = append(keys, cmp)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("sort: no fields in %q", spec)
+	}
+	return func(a, b *types.Task) int {
+		for _, k := range keys {
+			if c := k(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, nil
+}
+
+func compareField(f field) Compare {
+	return func(a, b *types.Task) int {
+		switch f.kind {
+		case kindInt:
+			return compareInt(f.get(a).(int64), f.get(b).(int64))
+		case kindNumber:
+			return compareFloat(f.get(a).(float64), f.get(b).(float64))
+		case kindBool:
+			x, y := f.get(a).(bool), f.get(b).(bool)
+			if x == y {
+				return 0
+			}
+			if !x {
+				return -1
+			}
+			return 1
+		case kindText:
+			return strings.Compare(strings.ToLower(f.get(a).(string)), strings.ToLower(f.get(b).(string)))
+		case kindTime:
+			x, y := f.get(a).(time.Time), f.get(b).(time.Time)
+			// unset times (no due date) go last
+			if x.IsZero() != y.IsZero() {
+				if x.IsZero() {
+					return 1
+				}
+				return -1
+			}
+			return x.Compare(y)
+		case kindPriority:
+			return compareInt(int64(f.get(a).(types.Priority).Rank()), int64(f.get(b).(types.Priority).Rank()))
+		case kindStatus:
+			return compareInt(int64(slices.Index(types.Statuses, f.get(a).(types.Status))), int64(slices.Index(types.Statuses, f.get(b).(types.Status))))
+		case kindTags:
+			return strings.Compare(strings.Join(f.get(a).([]string), ","), strings.Join(f.get(b).([]string), ","))
+		}
+		return 0
+	}
+}
+
+// Run keeps the tasks matching pred, sorts them with cmp when it is not nil and
+// returns at most limit of them starting at offset. A limit below 1 means no limit.
+func Run(tasks []*types.Task, pred Predicate, cmp Compare, offset, limit int) []*types.Task {
+	arr := make([]*types.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if pred == nil || pred(t) {
+			arr = append(arr, t)
+		}
+	}
+	if cmp != nil {
+		slices.SortStableFunc(arr, cmp)
+	}
+	if offset >= len(arr) {
+		return arr[:0]
+	}
+	arr = arr[offset:]
+	if limit > 0 && limit < len(arr) {
+		arr = arr[:limit]
+	}
+	return arr
+}
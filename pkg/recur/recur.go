@@ -0,0 +1,185 @@
+// Package recur parses the schedules of recurring tasks:
+//
+//	daily              every day
+//	weekdays           Monday to Friday
+//	weekly:mon,thu     on the given days of the week
+//	monthly:1,15       on the given days of the month, skipping months without that day
+//	cron:0 9 * * 1-5   a five field cron expression: minute hour day-of-month month day-of-week
+//
+// All but cron occur at midnight. Every form is compiled into a cron expression.
+package recur
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed schedule.
+type Schedule struct {
+	spec    string
+	minute  [60]bool
+	hour    [24]bool
+	dom     [32]bool
+	month   [13]bool
+	dow     [7]bool
+	anyDOM  bool
+	anyDOW  bool
+	minutes []int // matching minutes and hours, latest first
+	hours   []int
+}
+
+var weekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// Parse parses one of the schedules listed in the package documentation.
+func Parse(spec string) (*Schedule, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	var expr string
+	switch strings.ToLower(name) {
+	case "daily":
+		expr = "0 0 * * *"
+	case "weekdays":
+		expr = "0 0 * * 1-5"
+	case "weekly":
+		days := make([]string, 0)
+		for _, d := range strings.Split(arg, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			n, ok := weekdays[d[:min(3, len(d))]] // mon, monday
+			if !ok {
+				return nil, fmt.Errorf("schedule %q: unknown day %q, expected mon, tue, wed, thu, fri, sat or sun", spec, d)
+			}
+			days = append(days, strconv.Itoa(n))
+		}
+		expr = "0 0 * * " + strings.Join(days, ",")
+	case "monthly":
+		if arg == "" {
+			return nil, fmt.Errorf("schedule %q: give the days of the month, e.g. monthly:1", spec)
+		}
+		expr = "0 0 " + arg + " * *"
+	case "cron":
+		expr = arg
+	default:
+		return nil, fmt.Errorf("unknown schedule %q, expected daily, weekdays, weekly:<days>, monthly:<days> or cron:<expression>", spec)
+	}
+	s, err := parseCron(expr)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %q: %w", spec, err)
+	}
+	s.spec = spec
+	return s, nil
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+func parseCron(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("expected 5 cron fields, got %d", len(parts))
+	}
+	s := &Schedule{anyDOM: parts[2] == "*", anyDOW: parts[4] == "*"}
+	fields := []struct {
+		dst      []bool
+		min, max int
+	}{
+		{s.minute[:], 0, 59},
+		{s.hour[:], 0, 23},
+		{s.dom[:], 1, 31},
+		{s.month[:], 1, 12},
+		{s.dow[:], 0, 7}, // 7 is Sunday as well
+	}
+	for i, f := range fields {
+		if err := parseField(parts[i], f.dst, f.min, f.max); err != nil {
+			return nil, err
+		}
+	}
+	for m := 59; m >= 0; m-- {
+		if s.minute[m] {
+			s.minutes = append(s.minutes, m)
+		}
+	}
+	for h := 23; h >= 0; h-- {
+		if s.hour[h] {
+			s.hours = append(s.hours, h)
+		}
+	}
+	return s, nil
+}
+
+// parseField sets dst for every value of a cron field: *, n, a-b, lists and /step.
+func parseField(field string, dst []bool, lo, hi int) error {
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+		from, to := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(a); err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(b); err != nil {
+					return fmt.Errorf("invalid range %q", part)
+				}
+			} else if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return fmt.Errorf("%q is out of range %d-%d", part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			dst[v%len(dst)] = true // folds day-of-week 7 onto 0
+		}
+	}
+	return nil
+}
+
+// matchDay follows cron: when both day-of-month and day-of-week are restricted a day
+// matching either of them counts.
+func (s *Schedule) matchDay(t time.Time) bool {
+	if !s.month[t.Month()] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[t.Weekday()]
+	switch {
+	case s.anyDOM && s.anyDOW:
+		return true
+	case s.anyDOM:
+		return dow
+	case s.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Prev returns the latest occurrence at or before t, looking back at most five years.
+func (s *Schedule) Prev(t time.Time) (time.Time, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for i := 0; i < 5*366; i++ {
+		if s.matchDay(day) {
+			for _, h := range s.hours {
+				for _, m := range s.minutes {
+					at := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, t.Location())
+					if !at.After(t) {
+						return at, true
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return time.Time{}, false
+}
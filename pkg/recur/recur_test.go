@@ -0,0 +1,62 @@
+package recur
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrev(t *testing.T) {
+	// Thursday
+	now := time.Date(2026, time.October, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"daily", time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{"weekdays", time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{"weekly:mon,tue", time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)},
+		{"weekly:Sunday", time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{"monthly:1", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{"monthly:31", time.Date(2026, 8, 31, 0, 0, 0, 0, time.UTC)},
+		{"cron:0 9 * * 1-5", time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)},
+		{"cron:0 11 * * 1-5", time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC)},
+		{"cron:*/20 * * * *", time.Date(2026, 10, 15, 10, 20, 0, 0, time.UTC)},
+		{"cron:0 0 1 1 *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"cron:0 0 13 * 5", time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)}, // day of month or Friday
+		{"cron:0 0 * * 7", time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+			got, ok := s.Prev(now)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("an occurrence at now counts", func(t *testing.T) {
+		s, err := Parse("cron:30 10 * * *")
+		require.NoError(t, err)
+		got, ok := s.Prev(now)
+		require.True(t, ok)
+		assert.Equal(t, now, got)
+	})
+
+	t.Run("never", func(t *testing.T) {
+		s, err := Parse("cron:0 0 30 2 *")
+		require.NoError(t, err)
+		_, ok := s.Prev(now)
+		assert.False(t, ok)
+	})
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"", "hourly", "weekly:funday", "weekly:", "monthly", "monthly:0", "cron:* * * *", "cron:60 * * * *", "cron:*/0 * * * *", "cron:5-1 * * * *"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,155 @@
+// Package search is an inverted index over task descriptions. Words are split at
+// everything that is not a letter or a digit and folded to lower case. Every word of a
+// query has to match a word of a description, either fully or as its prefix.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Index maps words to the tasks using them. It is stored as json.
+type Index struct {
+	Terms map[string]map[int64]int `json:"terms"` // word → task ID → occurrences
+	Docs  map[int64]Doc            `json:"docs"`
+
+	sorted []string // words of Terms in order, for prefix lookups
+}
+
+// Doc is an indexed task.
+type Doc struct {
+	Words     []string  `json:"words"` // distinct words, to remove the task again
+	Length    int       `json:"length"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Result is a task matching a query. Higher scores rank first.
+type Result struct {
+	ID    int64   `json:"id"`
+	Score float64 `json:"score"`
+}
+
+func New() *Index {
+	return &Index{Terms: make(map[string]map[int64]int), Docs: make(map[int64]Doc)}
+}
+
+// Tokenize splits s into lower case words.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Add indexes the text of the task with the given id, replacing what was indexed for it.
+func (ix *Index) Add(id int64, text string, updated time.Time) {
+	ix.Remove(id)
+	words := Tokenize(text)
+	counts := make(map[string]int)
+	for _, w := range words {
+		counts[w]++
+	}
+	doc := Doc{Length: len(words), UpdatedAt: updated}
+	for w, n := range counts {
+		if ix.Terms[w] == nil {
+			ix.Terms[w] = make(map[int64]int)
+			ix.sorted = nil
+		}
+		ix.Terms[w][id] = n
+		doc.Words = append(doc.Words, w)
+	}
+	sort.Strings(doc.Words)
+	ix.Docs[id] = doc
+}
+
+// Remove drops the task with the given id from the index.
+func (ix *Index) Remove(id int64) {
+	doc, ok := ix.Docs[id]
+	if !ok {
+		return
+	}
+	for _, w := range doc.Words {
+		delete(ix.Terms[w], id)
+		if len(ix.Terms[w]) == 0 {
+			delete(ix.Terms, w)
+			ix.sorted = nil
+		}
+	}
+	delete(ix.Docs, id)
+}
+
+// prefixMatch weighs a word that only starts with the query word against a full match.
+const prefixMatch = 0.5
+
+// halfLife is the age at which recency adds half of what a task changed right now gets.
+const halfLife = 30 * 24 * time.Hour
+
+// Search returns the tasks matching every word of q, most relevant first. Relevance
+// sums for every query word how often the task uses it, weighed by how rare the word
+// is (tf-idf) and by whether it matched fully or as a prefix, divided by the square root
+// of the length of the description. Tasks changed recently up to double their score.
+func (ix *Index) Search(q string, now time.Time) []Result {
+	words := Tokenize(q)
+	if len(words) == 0 {
+		return []Result{}
+	}
+	var scores map[int64]float64
+	for _, qw := range words {
+		best := make(map[int64]float64)
+		for _, w := range ix.matches(qw) {
+			weight := 1.0
+			if w != qw {
+				weight = prefixMatch
+			}
+			idf := math.Log(1 + float64(len(ix.Docs))/float64(len(ix.Terms[w])))
+			for id, n := range ix.Terms[w] {
+				best[id] = max(best[id], float64(n)*idf*weight)
+			}
+		}
+		if scores == nil {
+			scores = best
+			continue
+		}
+		for id := range scores {
+			if s, ok := best[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	res := make([]Result, 0, len(scores))
+	for id, s := range scores {
+		doc := ix.Docs[id]
+		s /= math.Sqrt(float64(max(doc.Length, 1)))
+		age := max(now.Sub(doc.UpdatedAt), 0)
+		s *= 1 + 1/(1+float64(age)/float64(halfLife))
+		res = append(res, Result{ID: id, Score: s})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].ID > res[j].ID
+	})
+	return res
+}
+
+// matches returns the indexed words starting with w, w itself included.
+func (ix *Index) matches(w string) []string {
+	if ix.sorted == nil {
+		ix.sorted = make([]string, 0, len(ix.Terms))
+		for t := range ix.Terms {
+			ix.sorted = append(ix.sorted, t)
+		}
+		sort.Strings(ix.sorted)
+	}
+	res := make([]string, 0)
+	for i := sort.SearchStrings(ix.sorted, w); i < len(ix.sorted) && strings.HasPrefix(ix.sorted[i], w); i++ {
+		res = append(res, ix.sorted[i])
+	}
+	return res
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(res []Result) []int64 {
+	out := make([]int64, 0, len(res))
+	for _, r := range res {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"migrate", "postgres", "to", "v16", "über"}, Tokenize("Migrate Postgres to v16, Über!"))
+	assert.Empty(t, Tokenize(" -- "))
+}
+
+func TestSearch(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	ix := New()
+	ix.Add(1, "Postgres migration plan", now.AddDate(0, -6, 0))
+	ix.Add(2, "Postgres migration", now)
+	ix.Add(3, "migrate the billing service", now)
+	ix.Add(4, "buy milk", now)
+
+	t.Run("every word has to match", func(t *testing.T) {
+		assert.Equal(t, []int64{2, 1}, ids(ix.Search("postgres migration", now)))
+		assert.Empty(t, ix.Search("postgres milk", now))
+		assert.Empty(t, ix.Search("", now))
+	})
+
+	t.Run("case folding and prefixes", func(t *testing.T) {
+		assert.ElementsMatch(t, []int64{1, 2, 3}, ids(ix.Search("MIGR", now)))
+	})
+
+	t.Run("full matches rank above prefixes", func(t *testing.T) {
+		res := ix.Search("migrate", now)
+		assert.Equal(t, int64(3), res[0].ID)
+	})
+
+	t.Run("recency breaks even relevance", func(t *testing.T) {
+		ix.Add(5, "Postgres migration", now.AddDate(-1, 0, 0))
+		assert.Equal(t, []int64{2, 5}, ids(ix.Search("postgres migration", now))[:2])
+	})
+
+	t.Run("update and remove", func(t *testing.T) {
+		ix.Add(4, "buy oat milk", now)
+		assert.Equal(t, []int64{4}, ids(ix.Search("oat", now)))
+		ix.Remove(4)
+		assert.Empty(t, ix.Search("milk", now))
+		assert.NotContains(t, ix.Terms, "milk")
+	})
+}
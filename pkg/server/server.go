@@ -0,0 +1,385 @@
+// Package server exposes a task.Store as a JSON REST API.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/task"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+type Server struct {
+	store task.Store
+	mux   *http.ServeMux
+}
+
+// New returns a handler serving
+//
+//	POST   /tasks                      create a task
+//	GET    /tasks?year=&month=&day=    list tasks, all of them without parameters
+//	GET    /tasks?from=&to=            list tasks created in a range of days (YYYY-MM-DD)
+//	GET    /tasks/{id}                 get one task
+//	PATCH  /tasks/{id}?force=true      change description, status, priority, due_at, tags, parent_id
+//	                                   or depends_on; force marks a task done despite open dependencies
+//	DELETE /tasks/{id}?cascade=true    move a task to the trash, with cascade also its subtasks
+//	GET    /tasks/{id}/history         audit log of a task, also of a deleted one
+//	GET    /search?q=&limit=           tasks whose description matches the words of q, best first
+//	GET    /blocked                    list open tasks waiting for tasks that are not done
+//	GET    /trash                      list tasks in the trash
+//	POST   /trash/{id}/restore         take a task out of the trash
+//	DELETE /trash/{id}                 delete a task in the trash for good
+func New(store task.Store) *Server {
+	s := &Server{store: store, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /tasks", s.create)
+	s.mux.HandleFunc("GET /tasks", s.list)
+	s.mux.HandleFunc("GET /tasks/{id}", s.get)
+	s.mux.HandleFunc("PATCH /tasks/{id}", s.update)
+	s.mux.HandleFunc("DELETE /tasks/{id}", s.delete)
+	s.mux.HandleFunc("GET /tasks/{id}/history", s.history)
+	s.mux.HandleFunc("GET /search", s.search)
+	s.mux.HandleFunc("GET /blocked", s.blocked)
+	s.mux.HandleFunc("GET /trash", s.trash)
+	s.mux.HandleFunc("POST /trash/{id}/restore", s.restore)
+	s.mux.HandleFunc("DELETE /trash/{id}", s.purge)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// taskPatch holds the fields PATCH may change. Missing fields are left as they are,
+// a zero due_at or an empty tags list removes them.
+type taskPatch struct {
+	Description *string         `json:"description"`
+	Status      *types.Status   `json:"status"`
+	Done        *bool           `json:"done"` // older clients: true is status done, false is todo
+	Priority    *types.Priority `json:"priority"`
+	DueAt       *time.Time      `json:"due_at"`
+	Tags        *[]string       `json:"tags"`
+	Assignee    *string         `json:"assignee"`  // "" leaves the task unassigned
+	Estimate    *float64        `json:"estimate"`  // 0 removes the estimate
+	ParentID    *int64          `json:"parent_id"` // 0 makes the task a top-level one
+	DependsOn   *[]int64        `json:"depends_on"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	t := &types.Task{}
+	if err := json.NewDecoder(r.Body).Decode(t); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if t.Description == "" {
+		writeError(w, http.StatusBadRequest, errors.New("provide task description"))
+		return
+	}
+	if t.Priority != "" {
+		p, err := types.ParsePriority(string(t.Priority))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		t.Priority = p
+	}
+	t.Tags = types.NormalizeTags(t.Tags)
+	if _, err := types.ParseStatus(string(t.Status)); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := types.CheckEstimate(t.Estimate); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// ids, timestamps and the creator are always assigned by the store, time is only
+	// tracked through start and stop
+	t.ID = 0
+	t.CreatedAt = time.Time{}
+	t.UpdateAt = time.Time{}
+	t.DoneAt = time.Time{}
+	t.CreatedBy = ""
+	t.Timer = nil
+	t.TimeLog = nil
+	if err := task.Add(s.store, t); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if len(q) == 0 {
+		arr, err := s.store.List()
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, arr)
+		return
+	}
+
+	// unlike ls, no part of the date is implied: year=2025 is the whole year, month=5
+	// May of every year
+	f := &types.Filter{}
+	for name, dst := range map[string]*time.Time{"from": &f.From, "to": &f.To} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		d, err := types.ParseDate(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid "+name+", expected "+types.DateLayout))
+			return
+		}
+		*dst = d
+	}
+	for name, dst := range map[string]*int{"year": &f.Year, "month": &f.Month, "day": &f.Day} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid "+name))
+			return
+		}
+		*dst = n
+	}
+	arr, err := s.store.Query(f)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, arr)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	t, err := s.store.Get(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	p := &taskPatch{}
+	if err := json.NewDecoder(r.Body).Decode(p); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	t, err := s.store.Get(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if p.Description != nil {
+		if *p.Description == "" {
+			writeError(w, http.StatusBadRequest, errors.New("description can not be empty"))
+			return
+		}
+		t.Description = *p.Description
+	}
+	if p.Done != nil && p.Status == nil {
+		st := types.StatusTodo
+		if *p.Done {
+			st = types.StatusDone
+		}
+		p.Status = &st
+	}
+	if p.Status != nil {
+		st, err := types.ParseStatus(string(*p.Status))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		t.Status = st
+	}
+	if p.Priority != nil {
+		prio, err := types.ParsePriority(string(*p.Priority))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		t.Priority = prio
+	}
+	if p.DueAt != nil {
+		t.DueAt = *p.DueAt
+	}
+	if p.Tags != nil {
+		t.Tags = types.NormalizeTags(*p.Tags)
+	}
+	if p.Assignee != nil {
+		t.Assignee = strings.TrimSpace(*p.Assignee)
+	}
+	if p.Estimate != nil {
+		if err := types.CheckEstimate(*p.Estimate); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		t.Estimate = *p.Estimate
+	}
+	if p.ParentID != nil {
+		t.ParentID = *p.ParentID
+	}
+	if p.DependsOn != nil {
+		t.DependsOn = *p.DependsOn
+	}
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	t.UpdateAt = time.Now().Local()
+	if err := task.Save(s.store, t, force); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+	if _, err := task.Remove(s.store, id, cascade); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("q") == "" {
+		writeError(w, http.StatusBadRequest, errors.New("provide words to search for in q"))
+		return
+	}
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid limit"))
+			return
+		}
+		limit = n
+	}
+	arr, err := task.Search(s.store, q.Get("q"), time.Now().Local())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if limit > 0 && len(arr) > limit {
+		arr = arr[:limit]
+	}
+	writeJSON(w, http.StatusOK, arr)
+}
+
+func (s *Server) blocked(w http.ResponseWriter, r *http.Request) {
+	arr, err := task.Blocked(s.store)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, arr)
+}
+
+func (s *Server) trash(w http.ResponseWriter, r *http.Request) {
+	arr, err := s.store.Trash()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, arr)
+}
+
+func (s *Server) restore(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.Restore(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	t, err := s.store.Get(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (s *Server) purge(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.store.Purge(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	events, err := s.store.History(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id < 1 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid task id"))
+		return 0, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// writeStoreError maps store errors to status codes.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		writeError(w, http.StatusNotFound, errors.New("task does not exist"))
+	case task.IsConflict(err):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, utils.ErrStorageBusy):
+		writeError(w, http.StatusServiceUnavailable, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"taskTracker/pkg/task"
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&v))
+	return v
+}
+
+func TestServer(t *testing.T) {
+	h := New(task.NewMemStore())
+
+	t.Run("create", func(t *testing.T) {
+		rec := do(t, h, http.MethodPost, "/tasks", `{"description":"write tests","id":42}`)
+		require.Equal(t, http.StatusCreated, rec.Code)
+		res := decode[types.Task](t, rec)
+		assert.Equal(t, int64(1), res.ID)
+		assert.Equal(t, "write tests", res.Description)
+		assert.False(t, res.CreatedAt.IsZero())
+	})
+
+	t.Run("create without description -> 400", func(t *testing.T) {
+		rec := do(t, h, http.MethodPost, "/tasks", `{"done":true}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = do(t, h, http.MethodPost, "/tasks", `not json`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rec := do(t, h, http.MethodGet, "/tasks/1", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "write tests", decode[types.Task](t, rec).Description)
+	})
+
+	t.Run("get missing -> 404, bad id -> 400", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/tasks/99", "").Code)
+		assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/tasks/abc", "").Code)
+	})
+
+	t.Run("patch keeps missing fields", func(t *testing.T) {
+		rec := do(t, h, http.MethodPatch, "/tasks/1", `{"done":true}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		res := decode[types.Task](t, rec)
+		assert.True(t, res.Done())
+		assert.Equal(t, "write tests", res.Description)
+
+		rec = do(t, h, http.MethodPatch, "/tasks/1", `{"description":"write more tests"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		res = decode[types.Task](t, rec)
+		assert.True(t, res.Done())
+		assert.Equal(t, "write more tests", res.Description)
+	})
+
+	t.Run("patch status", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, do(t, h, http.MethodPatch, "/tasks/1", `{"status":"in_progress"}`).Code)
+		rec := do(t, h, http.MethodPatch, "/tasks/1", `{"status":"cancelled"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, types.StatusCancelled, decode[types.Task](t, rec).Status)
+
+		assert.Equal(t, http.StatusConflict, do(t, h, http.MethodPatch, "/tasks/1", `{"status":"done"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPatch, "/tasks/1", `{"status":"finished"}`).Code)
+
+		rec = do(t, h, http.MethodPatch, "/tasks/1", `{"done":false}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, types.StatusTodo, decode[types.Task](t, rec).Status)
+		require.Equal(t, http.StatusOK, do(t, h, http.MethodPatch, "/tasks/1", `{"done":true}`).Code)
+	})
+
+	t.Run("patch priority, due date and tags", func(t *testing.T) {
+		rec := do(t, h, http.MethodPatch, "/tasks/1", `{"priority":"High","tags":["Ops","ops"],"due_at":"2030-01-01T00:00:00Z"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		res := decode[types.Task](t, rec)
+		assert.Equal(t, types.PriorityHigh, res.Priority)
+		assert.Equal(t, []string{"ops"}, res.Tags)
+		assert.Equal(t, 2030, res.DueAt.Year())
+
+		rec = do(t, h, http.MethodPatch, "/tasks/1", `{"tags":[],"due_at":"0001-01-01T00:00:00Z"}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		res = decode[types.Task](t, rec)
+		assert.Empty(t, res.Tags)
+		assert.True(t, res.DueAt.IsZero())
+
+		assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPatch, "/tasks/1", `{"priority":"asap"}`).Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, do(t, h, http.MethodPost, "/tasks", `{"description":"second"}`).Code)
+
+		rec := do(t, h, http.MethodGet, "/tasks", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, decode[[]types.Task](t, rec), 2)
+
+		now := time.Now().Local()
+		rec = do(t, h, http.MethodGet, "/tasks?year="+now.Format("2006")+"&month="+now.Format("1")+"&day="+now.Format("2"), "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, decode[[]types.Task](t, rec), 2)
+
+		rec = do(t, h, http.MethodGet, "/tasks?year=2001", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, decode[[]types.Task](t, rec), 0)
+
+		// tasks of another month of this year
+		s := task.NewMemStore()
+		other := now.AddDate(0, 1, 0)
+		if other.Year() != now.Year() {
+			other = now.AddDate(0, -1, 0)
+		}
+		require.NoError(t, s.Create(&types.Task{Description: "now", CreatedAt: now}))
+		require.NoError(t, s.Create(&types.Task{Description: "other month", CreatedAt: other}))
+		rec = do(t, New(s), http.MethodGet, "/tasks?year="+now.Format("2006"), "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, decode[[]types.Task](t, rec), 2, "the whole year")
+		rec = do(t, New(s), http.MethodGet, "/tasks?month="+other.Format("1"), "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, decode[[]types.Task](t, rec), 1)
+
+		rec = do(t, h, http.MethodGet, "/tasks?from="+now.AddDate(0, -2, 0).Format(types.DateLayout), "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, decode[[]types.Task](t, rec), 2)
+
+		assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/tasks?month=x", "").Code)
+		assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/tasks?to=tomorrow", "").Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/tasks/1", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodDelete, "/tasks/1", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/tasks/1", "").Code)
+	})
+
+	t.Run("trash, restore and purge", func(t *testing.T) {
+		rec := do(t, h, http.MethodGet, "/trash", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		trash := decode[[]types.Task](t, rec)
+		require.Len(t, trash, 1)
+		assert.Equal(t, int64(1), trash[0].ID)
+
+		rec = do(t, h, http.MethodPost, "/trash/1/restore", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodPost, "/trash/1/restore", "").Code)
+		assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodDelete, "/trash/1", "").Code)
+
+		assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/tasks/1", "").Code)
+		assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/trash/1", "").Code)
+		assert.Empty(t, decode[[]types.Task](t, do(t, h, http.MethodGet, "/trash", "")))
+	})
+
+	t.Run("history outlives the task", func(t *testing.T) {
+		rec := do(t, h, http.MethodGet, "/tasks/1/history", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		events := decode[[]types.Event](t, rec)
+		require.NotEmpty(t, events)
+		assert.Equal(t, types.OpCreate, events[0].Op)
+		assert.Equal(t, types.OpPurge, events[len(events)-1].Op)
+
+		assert.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/tasks/99/history", "").Code)
+	})
+
+	t.Run("wrong method -> 405", func(t *testing.T) {
+		assert.Equal(t, http.StatusMethodNotAllowed, do(t, h, http.MethodPut, "/tasks/1", "{}").Code)
+	})
+}
+
+func TestServerSubtasks(t *testing.T) {
+	h := New(task.NewMemStore())
+	require.Equal(t, http.StatusCreated, do(t, h, http.MethodPost, "/tasks", `{"description":"release"}`).Code)
+	rec := do(t, h, http.MethodPost, "/tasks", `{"description":"changelog","parent_id":1}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, int64(1), decode[types.Task](t, rec).ParentID)
+
+	assert.Equal(t, http.StatusConflict, do(t, h, http.MethodPost, "/tasks", `{"description":"x","parent_id":9}`).Code)
+	assert.Equal(t, http.StatusConflict, do(t, h, http.MethodPatch, "/tasks/1", `{"status":"done"}`).Code)
+	assert.Equal(t, http.StatusConflict, do(t, h, http.MethodPatch, "/tasks/1", `{"parent_id":2}`).Code)
+	assert.Equal(t, http.StatusConflict, do(t, h, http.MethodDelete, "/tasks/1", "").Code)
+
+	rec = do(t, h, http.MethodPatch, "/tasks/1", `{"depends_on":[2]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, decode[[]types.Task](t, do(t, h, http.MethodGet, "/blocked", "")), 1)
+	assert.Equal(t, http.StatusConflict, do(t, h, http.MethodPatch, "/tasks/2", `{"depends_on":[1]}`).Code)
+	assert.Equal(t, http.StatusOK, do(t, h, http.MethodPatch, "/tasks/1", `{"depends_on":[]}`).Code)
+
+	assert.Equal(t, http.StatusNoContent, do(t, h, http.MethodDelete, "/tasks/1?cascade=true", "").Code)
+	assert.Len(t, decode[[]types.Task](t, do(t, h, http.MethodGet, "/trash", "")), 2)
+}
+
+func TestServerAssign(t *testing.T) {
+	s := task.NewMemStore()
+	s.Actor = "alice"
+	h := New(s)
+	rec := do(t, h, http.MethodPost, "/tasks", `{"description":"review","assignee":"bob"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	created := decode[types.Task](t, rec)
+	assert.Equal(t, "alice", created.CreatedBy)
+	assert.Equal(t, "bob", created.Assignee)
+
+	rec = do(t, h, http.MethodPatch, "/tasks/1", `{"assignee":"carol","created_by":"mallory"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	updated := decode[types.Task](t, rec)
+	assert.Equal(t, "carol", updated.Assignee)
+	assert.Equal(t, "alice", updated.CreatedBy)
+
+	rec = do(t, h, http.MethodPost, "/tasks", `{"description":"forged","created_by":"mallory",`+
+		`"timer":{"user":"mallory","start":"2026-01-05T09:00:00Z"},`+
+		`"time_log":[{"user":"mallory","start":"2026-01-05T09:00:00Z","end":"2026-01-05T17:00:00Z"}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	created = decode[types.Task](t, rec)
+	assert.Equal(t, "alice", created.CreatedBy)
+	assert.Nil(t, created.Timer, "timers only run through start")
+	assert.Empty(t, created.TimeLog)
+}
+
+func TestServerEstimate(t *testing.T) {
+	h := New(task.NewMemStore())
+	rec := do(t, h, http.MethodPost, "/tasks", `{"description":"review","estimate":3,"done_at":"2020-01-01T00:00:00Z"}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	created := decode[types.Task](t, rec)
+	assert.Equal(t, 3.0, created.Estimate)
+	assert.True(t, created.DoneAt.IsZero(), "done_at is kept by the store")
+	assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/tasks", `{"description":"x","estimate":-1}`).Code)
+
+	rec = do(t, h, http.MethodPatch, "/tasks/1", `{"estimate":0.5,"status":"done"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	updated := decode[types.Task](t, rec)
+	assert.Equal(t, 0.5, updated.Estimate)
+	assert.False(t, updated.DoneAt.IsZero())
+	assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPatch, "/tasks/1", `{"estimate":-2}`).Code)
+}
+
+func TestServerSearch(t *testing.T) {
+	h := New(task.NewMemStore())
+	for _, desc := range []string{"Postgres migration", "buy milk", "migrate Postgres replicas"} {
+		require.Equal(t, http.StatusCreated, do(t, h, http.MethodPost, "/tasks", `{"description":"`+desc+`"}`).Code)
+	}
+	rec := do(t, h, http.MethodGet, "/search?q=postgres+migr", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, decode[[]types.Task](t, rec), 2)
+	assert.Len(t, decode[[]types.Task](t, do(t, h, http.MethodGet, "/search?q=postgres&limit=1", "")), 1)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/search", "").Code)
+}
+
+func TestServerConcurrentCreateDB(t *testing.T) {
+	s, err := task.OpenDBStore(filepath.Join(t.TempDir(), "tasks.db"), time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	h := New(s)
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusCreated, do(t, h, http.MethodPost, "/tasks", `{"description":"parallel"}`).Code)
+		}()
+	}
+	wg.Wait()
+
+	all, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, all, n, "every POST got its own id")
+	for _, tk := range all {
+		events, err := s.History(tk.ID)
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+	}
+}
@@ -0,0 +1,28 @@
+package task
+
+import (
+	"path/filepath"
+	"strings"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+)
+
+// logFile is the audit log of the tasks kept in taskFile: the same <year>/<month> below
+// logDir, so SearchByID finds the log of a task even after it was deleted.
+func logFile(tStorage, logDir, taskFile string) (string, error) {
+	rel, err := filepath.Rel(tStorage, taskFile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(logDir, strings.TrimSuffix(rel, ".json")+".log"), nil
+}
+
+// appendEvent stages ev into the audit log fPath, so it is written in the same
+// transaction as the change it records.
+func appendEvent(tx *utils.Tx, fPath string, ev types.Event) error {
+	data, err := utils.EncodeEvent(ev)
+	if err != nil {
+		return err
+	}
+	return tx.Append(fPath, data)
+}
@@ -1,195 +1,228 @@
 package task
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"taskTracker/pkg/types"
 	"taskTracker/pkg/utils"
 	"time"
 )
 
-const (
-	STORAGE_LAST_ID = "storage/lastID.json"
-	TASK_STORAGE    = "storage/tasks"
-	INDEX_STORAGE   = "storage/index"
-	LOG_STORAGE     = "storage/logs"
-)
-
-func CreateTask(fPath, desc string, status bool, lastID int64) error {
-	year, month, _ := time.Now().Local().Date()
+// CreateTask stages t into the month file of its creation date, moves lastID and the year
+// index forward and adds t to the ID and full-text indexes.
+func CreateTask(tx *utils.Tx, t *types.Task, tStorage, iStorage, lastIDPath string) error {
+	year, month, _ := t.CreatedAt.Date()
 	tMap := make(map[int64]*types.Task)
 	iMap := make(map[int][]int64)
 
-	task := types.Task{ID: lastID + 1, Description: desc, Done: status, CreatedAt: time.Now().Local(), UpdateAt: time.Now().Local()}
-
-	if err := utils.DecodeTasks(fPath, tMap); err != nil {
-		log.Fatal(err)
+	fPath := monthFile(tStorage, t.CreatedAt)
+	if err := decodeTasks(tx, fPath, tMap); err != nil && !os.IsNotExist(err) {
+		return err
 	}
-	tMap[task.ID] = &task
+	tMap[t.ID] = t
 
-	if err := utils.EncodeTasks(fPath, tMap); err != nil {
+	if err := tx.EncodeTasks(fPath, tMap); err != nil {
 		return err
 	}
-	if err := utils.WriteLastID(task.ID, STORAGE_LAST_ID); err != nil {
+	if err := tx.WriteLastID(t.ID, lastIDPath); err != nil {
 		return err
 	}
 
-	iFile := filepath.Join(INDEX_STORAGE, fmt.Sprintf("%v.json", year))
-	if err := utils.DecodeIndex(iFile, iMap); err != nil {
+	iFile := filepath.Join(iStorage, fmt.Sprintf("%v.json", year))
+	if err := decodeIndex(tx, iFile, iMap); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	arr := iMap[int(month)]
 	if len(arr) > 1 {
-		arr[1] = task.ID // in order to keep only first id and last id of the tasks created at month
+		arr[1] = t.ID // in order to keep only first id and last id of the tasks created at month
 	} else {
-		arr = append(arr, task.ID)
+		arr = append(arr, t.ID)
 	}
 	iMap[int(month)] = arr
-	if err := utils.EncodeIndex(iFile, iMap); err != nil {
+	if err := tx.EncodeIndex(iFile, iMap); err != nil {
+		return err
+	}
+
+	if err := setID(tx, iStorage, tStorage, t.ID, idLocation{year, int(month)}); err != nil {
 		return err
 	}
-	return nil
+	return indexText(tx, iStorage, tStorage, t.ID, t)
 }
 
-// Update updates the task
-func Update(id int64, done bool, desc, targetFile string) error {
+// monthFile is the file of tStorage holding the tasks created in the month of created.
+func monthFile(tStorage string, created time.Time) string {
+	year, month, _ := created.Date()
+	return filepath.Join(tStorage, strconv.Itoa(year), fmt.Sprintf("%d.json", month))
+}
+
+// Update stages replacing the stored task with the same ID as t, reindexes its
+// description when it changed and returns the task it replaces.
+func Update(tx *utils.Tx, t *types.Task, targetFile, iStorage, tStorage string) (*types.Task, error) {
 	tMap := make(map[int64]*types.Task)
-	if err := utils.DecodeTasks(targetFile, tMap); err != nil {
-		return err
+	if err := decodeTasks(tx, targetFile, tMap); err != nil {
+		return nil, err
 	}
-	t := tMap[id]
-	if desc != "" {
-		t.Description = desc
+	old, ok := tMap[t.ID]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if err := prepareUpdate(old, t); err != nil {
+		return nil, err
 	}
-	t.Done = done
-	t.UpdateAt = time.Now().Local()
-	tMap[id] = t
+	tMap[t.ID] = t
 
-	if err := utils.EncodeTasks(targetFile, tMap); err != nil {
-		return err
+	if err := tx.EncodeTasks(targetFile, tMap); err != nil {
+		return nil, err
+	}
+	if old.Description != t.Description {
+		if err := indexText(tx, iStorage, tStorage, t.ID, t); err != nil {
+			return nil, err
+		}
 	}
-	return nil
+	return old, nil
 }
 
-func Delete(id int64, targetFile string) error {
+// SetDeleted stages moving the task with the given id to the trash at the time at, or
+// taking it out of the trash when at is zero. It returns the task before and after.
+// Trashing a trashed task or restoring one that is not in the trash fails with os.ErrNotExist.
+func SetDeleted(tx *utils.Tx, id int64, targetFile string, at time.Time) (*types.Task, *types.Task, error) {
 	tMap := make(map[int64]*types.Task)
-	if err := utils.DecodeTasks(targetFile, tMap); err != nil {
-		return err
+	if err := decodeTasks(tx, targetFile, tMap); err != nil {
+		return nil, nil, err
 	}
-	delete(tMap, id)
-	if err := utils.EncodeTasks(targetFile, tMap); err != nil {
-		return err
+	old, ok := tMap[id]
+	if !ok || old.Deleted() == !at.IsZero() {
+		return nil, nil, os.ErrNotExist
 	}
-	return nil
+	updated := *old
+	updated.DeletedAt = at
+	tMap[id] = &updated
+	if err := tx.EncodeTasks(targetFile, tMap); err != nil {
+		return nil, nil, err
+	}
+	return old, &updated, nil
 }
 
-// SearchByID search path to file where task was created using index (iStorage should be INDEX_STORAGE)
-func SearchByID(id int64, iStorage, tStoarge string) (string, error) {
-	wg := sync.WaitGroup{}
-	resChan := make(chan string)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	files, err := os.ReadDir(iStorage)
-	if err != nil {
-		return "", err
+// Delete stages removing the task with the given id for good, also from the full-text
+// index, and returns it.
+func Delete(tx *utils.Tx, id int64, targetFile, iStorage, tStorage string) (*types.Task, error) {
+	tMap := make(map[int64]*types.Task)
+	if err := decodeTasks(tx, targetFile, tMap); err != nil {
+		return nil, err
 	}
+	old, ok := tMap[id]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	delete(tMap, id)
+	if err := tx.EncodeTasks(targetFile, tMap); err != nil {
+		return nil, err
+	}
+	if err := indexText(tx, iStorage, tStorage, id, nil); err != nil {
+		return nil, err
+	}
+	return old, nil
+}
 
-	for _, file := range files {
-		iMap := make(map[int][]int64)
-		if file.IsDir() {
-			continue
-		}
-		if err := utils.DecodeIndex(filepath.Join(iStorage, file.Name()), iMap); err != nil {
-			continue
+// decodeTasks reads a month file, preferring what tx has already staged for it.
+func decodeTasks(tx *utils.Tx, fPath string, dst map[int64]*types.Task) error {
+	if f, ok := tx.Pending(fPath); ok {
+		if f.Remove {
+			return os.ErrNotExist
 		}
-		wg.Add(1)
-		go func(id int64, m map[int][]int64, fName string) {
-			defer wg.Done()
-			for k, val := range m {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					if len(val) < 2 {
-						if id == val[0] {
-							resChan <- fmt.Sprintf("%v/%v/%v.json", tStoarge, fName, k)
-							return
-						}
-						continue
-					}
-					if val[0] <= id && id <= val[1] {
-						resChan <- fmt.Sprintf("%v/%v/%v.json", tStoarge, fName, k)
-						return
-					}
-				}
-			}
-		}(id, iMap, strings.Split(file.Name(), ".")[0]) // split in order to get only year without file type
+		return json.Unmarshal(f.Data, &dst)
 	}
+	return utils.DecodeTasks(fPath, dst)
+}
 
-	go func() {
-		wg.Wait()
-		close(resChan)
-	}()
-	res, ok := <-resChan
-	if !ok {
-		cancel()
-		return "", os.ErrNotExist
+// decodeIndex reads a year index, preferring what tx has already staged for it.
+func decodeIndex(tx *utils.Tx, fPath string, dst map[int][]int64) error {
+	if f, ok := tx.Pending(fPath); ok {
+		if f.Remove {
+			return os.ErrNotExist
+		}
+		return json.Unmarshal(f.Data, &dst)
 	}
-	return res, nil
+	return utils.DecodeIndex(fPath, dst)
 }
 
-func GetToday(lastID int64, fPath string) ([]*types.Task, error) {
-	dst := make([]*types.Task, 0)
-	m := make(map[int64]*types.Task)
-	if err := utils.DecodeTasks(fPath, m); err != nil {
-		return nil, err
-	}
-	y, mt, d := time.Now().Local().Date()
-	_, ok := m[lastID]
-	if !ok {
-		return nil, os.ErrInvalid
-	}
-	elem := m[lastID].CreatedAt
-	for ok && elem.Year() == y && elem.Month() == mt && elem.Day() == d {
-		dst = append(dst, m[lastID])
-		lastID--
-		_, ok = m[lastID]
-		if !ok {
-			break
-		}
-		elem = m[lastID].CreatedAt
+// SearchByID returns the month file of the task with the given id, looked up in the ID
+// index of iStorage. IDs of purged tasks still lead to the month they were created in,
+// where their audit log is.
+func SearchByID(id int64, iStorage, tStorage string) (string, error) {
+	loc, err := lookupID(id, iStorage, tStorage)
+	if err != nil {
+		return "", err
 	}
-	return dst, nil
+	return loc.file(tStorage), nil
 }
 
+// GetByID reads the task from the month file fPath. Trashed tasks count as missing.
 func GetByID(id int64, fPath string) (*types.Task, error) {
 	m := make(map[int64]*types.Task)
 	if err := utils.DecodeTasks(fPath, m); err != nil {
 		return nil, err
 	}
-	if _, ok := m[id]; !ok {
+	if t, ok := m[id]; !ok || t.Deleted() {
 		return nil, os.ErrNotExist
 	}
 	return m[id], nil
 }
 
-func GetByDate(tStoragePath string, f *types.Filter) ([]*types.Task, error){
+// GetByDate returns the tasks passing f sorted by CreatedAt, trashed ones only with f.Trashed. Only month files that the
+// year index lists and that can hold matching tasks are read.
+func GetByDate(tStoragePath, iStoragePath string, f *types.Filter) ([]*types.Task, error) {
 	arr := make([]*types.Task, 0)
-	tMap := make(map[int64]*types.Task)
-	fPath := filepath.Join(tStoragePath, strconv.Itoa(f.Year), fmt.Sprintf("%d.json", f.Month))
-	if err := utils.DecodeTasks(fPath, tMap); err != nil{
+	files, err := os.ReadDir(iStoragePath)
+	if err != nil {
 		return nil, err
 	}
-	for _, t := range tMap{
-		arr = append(arr, t)
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		year, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil || (f.Year > 0 && year != f.Year) {
+			continue
+		}
+		iMap := make(map[int][]int64)
+		if err := utils.DecodeIndex(filepath.Join(iStoragePath, file.Name()), iMap); err != nil {
+			return nil, err
+		}
+		for month := range iMap {
+			if !f.MatchMonth(year, time.Month(month)) {
+				continue
+			}
+			tMap := make(map[int64]*types.Task)
+			fPath := filepath.Join(tStoragePath, strconv.Itoa(year), fmt.Sprintf("%d.json", month))
+			if err := utils.DecodeTasks(fPath, tMap); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			for _, t := range tMap {
+				if f.MatchTask(t) {
+					arr = append(arr, t)
+				}
+			}
+		}
 	}
-
+	sortByCreated(arr)
 	return arr, nil
 }
+
+// sortByCreated orders tasks by creation time, ID breaking ties.
+func sortByCreated(arr []*types.Task) {
+	sort.Slice(arr, func(i, j int) bool {
+		if !arr[i].CreatedAt.Equal(arr[j].CreatedAt) {
+			return arr[i].CreatedAt.Before(arr[j].CreatedAt)
+		}
+		return arr[i].ID < arr[j].ID
+	})
+}
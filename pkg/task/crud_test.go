@@ -0,0 +1,231 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetByDate(t *testing.T) {
+	s := newTestJSONStore(t)
+	dates := []time.Time{
+		time.Date(2025, time.December, 31, 22, 0, 0, 0, time.Local),
+		time.Date(2026, time.January, 2, 8, 0, 0, 0, time.Local),
+		time.Date(2026, time.January, 1, 9, 0, 0, 0, time.Local), // created later, dated earlier
+		time.Date(2026, time.February, 14, 12, 0, 0, 0, time.Local),
+		time.Date(2026, time.March, 1, 0, 0, 0, 0, time.Local),
+	}
+	for _, at := range dates {
+		require.NoError(t, s.Create(&types.Task{Description: at.Format(types.DateLayout), CreatedAt: at, UpdateAt: at}))
+	}
+
+	ids := func(arr []*types.Task) []int64 {
+		res := make([]int64, 0, len(arr))
+		for _, elem := range arr {
+			res = append(res, elem.ID)
+		}
+		return res
+	}
+
+	t.Run("month sorted by creation time", func(t *testing.T) {
+		arr, err := GetByDate(s.TaskDir, s.IndexDir, &types.Filter{Year: 2026, Month: 1})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3, 2}, ids(arr))
+	})
+
+	t.Run("day", func(t *testing.T) {
+		arr, err := GetByDate(s.TaskDir, s.IndexDir, &types.Filter{Year: 2026, Month: 1, Day: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2}, ids(arr))
+	})
+
+	t.Run("whole year", func(t *testing.T) {
+		arr, err := GetByDate(s.TaskDir, s.IndexDir, &types.Filter{Year: 2026})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{3, 2, 4, 5}, ids(arr))
+	})
+
+	t.Run("range across years", func(t *testing.T) {
+		f := &types.Filter{
+			From: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.Local),
+			To:   time.Date(2026, time.February, 14, 0, 0, 0, 0, time.Local),
+		}
+		arr, err := GetByDate(s.TaskDir, s.IndexDir, f)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{1, 3, 2, 4}, ids(arr))
+	})
+
+	t.Run("nothing matches", func(t *testing.T) {
+		arr, err := GetByDate(s.TaskDir, s.IndexDir, &types.Filter{Year: 2020})
+		require.NoError(t, err)
+		assert.Empty(t, arr)
+	})
+}
+
+func TestSearchByID(t *testing.T) {
+	s := newTestJSONStore(t)
+	for _, at := range []time.Time{
+		time.Date(2025, time.December, 31, 22, 0, 0, 0, time.Local),
+		time.Date(2026, time.January, 2, 8, 0, 0, 0, time.Local),
+		time.Date(2026, time.January, 3, 8, 0, 0, 0, time.Local),
+	} {
+		require.NoError(t, s.Create(&types.Task{Description: "task", CreatedAt: at, UpdateAt: at}))
+	}
+	jan := filepath.Join(s.TaskDir, "2026", "1.json")
+
+	path, err := SearchByID(1, s.IndexDir, s.TaskDir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(s.TaskDir, "2025", "12.json"), path, "a month with a single task")
+	path, err = SearchByID(3, s.IndexDir, s.TaskDir)
+	require.NoError(t, err)
+	assert.Equal(t, jan, path)
+	_, err = SearchByID(4, s.IndexDir, s.TaskDir)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	t.Run("purged tasks lead to their history", func(t *testing.T) {
+		require.NoError(t, s.Delete(2))
+		require.NoError(t, s.Purge(2))
+		path, err := SearchByID(2, s.IndexDir, s.TaskDir)
+		require.NoError(t, err)
+		assert.Equal(t, jan, path)
+		_, err = s.Get(2)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("undo of a create drops the id", func(t *testing.T) {
+		require.NoError(t, s.Create(&types.Task{Description: "undone"}))
+		_, err := s.Undo(1)
+		require.NoError(t, err)
+		_, err = SearchByID(4, s.IndexDir, s.TaskDir)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("a create stages only its own record", func(t *testing.T) {
+		tx := utils.Begin(s.JournalPath, "create")
+		at := time.Date(2026, time.February, 1, 8, 0, 0, 0, time.Local)
+		require.NoError(t, CreateTask(tx, &types.Task{ID: 6, Description: "task", CreatedAt: at}, s.TaskDir, s.IndexDir, s.LastIDPath))
+		f, ok := tx.Pending(filepath.Join(s.IndexDir, idIndexName))
+		require.True(t, ok)
+		assert.True(t, f.At)
+		assert.Equal(t, int64(4*idRecordSize), f.Offset, "undo left ID 4 unused")
+		assert.Equal(t, "000000\n202602\n", string(f.Data), "ID 5 is unused")
+	})
+
+	t.Run("storage without an ID index", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(s.IndexDir, idIndexName)))
+		path, err := SearchByID(3, s.IndexDir, s.TaskDir)
+		require.NoError(t, err)
+		assert.Equal(t, jan, path)
+
+		require.NoError(t, s.Create(&types.Task{Description: "new"}))
+		data, err := os.ReadFile(filepath.Join(s.IndexDir, idIndexName))
+		require.NoError(t, err)
+		require.Len(t, data, 4*idRecordSize, "the first create saves the index built from the month files")
+		assert.Equal(t, "202512\n000000\n202601\n", string(data[:3*idRecordSize]), "a rebuilt index does not know purged tasks")
+	})
+}
+
+// rangeScan is how SearchByID found a task before the ID index: a goroutine per year
+// index checks the [first, last] range of every month, and the month files in range
+// are read until one holds the task.
+func rangeScan(id int64, iStorage, tStorage string) (string, error) {
+	wg := sync.WaitGroup{}
+	resChan := make(chan string)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files, err := os.ReadDir(iStorage)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		iMap := make(map[int][]int64)
+		if file.IsDir() || file.Name() == idIndexName {
+			continue
+		}
+		if err := utils.DecodeIndex(filepath.Join(iStorage, file.Name()), iMap); err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(m map[int][]int64, fName string) {
+			defer wg.Done()
+			for k, val := range m {
+				if len(val) == 0 || id < val[0] || id > val[len(val)-1] {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case resChan <- fmt.Sprintf("%v/%v/%v.json", tStorage, fName, k):
+				}
+			}
+		}(iMap, strings.Split(file.Name(), ".")[0])
+	}
+	go func() {
+		wg.Wait()
+		close(resChan)
+	}()
+	for res := range resChan {
+		tMap := make(map[int64]*types.Task)
+		if err := utils.DecodeTasks(res, tMap); err != nil {
+			continue
+		}
+		if _, ok := tMap[id]; ok {
+			return res, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+// BenchmarkSearchByID compares the ID index with the range scan on five years of 100
+// tasks a month.
+func BenchmarkSearchByID(b *testing.B) {
+	dir := b.TempDir()
+	tStorage, iStorage := filepath.Join(dir, "tasks"), filepath.Join(dir, "index")
+	require.NoError(b, os.MkdirAll(iStorage, 0755))
+	const perMonth = 100
+	ids := make(map[int64]idLocation)
+	var id int64
+	for year := 2021; year <= 2025; year++ {
+		require.NoError(b, os.MkdirAll(filepath.Join(tStorage, fmt.Sprint(year)), 0755))
+		iMap := make(map[int][]int64)
+		for month := 1; month <= 12; month++ {
+			tMap := make(map[int64]*types.Task)
+			at := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+			for i := 0; i < perMonth; i++ {
+				id++
+				tMap[id] = &types.Task{ID: id, Description: "task", Status: types.StatusTodo, CreatedAt: at, UpdateAt: at}
+				ids[id] = idLocation{year, month}
+			}
+			iMap[month] = []int64{id - perMonth + 1, id}
+			require.NoError(b, utils.EncodeTasks(idLocation{year, month}.file(tStorage), tMap))
+		}
+		require.NoError(b, utils.EncodeIndex(filepath.Join(iStorage, fmt.Sprintf("%d.json", year)), iMap))
+	}
+	tx := utils.Begin(filepath.Join(dir, "journal.json"), "bench")
+	require.NoError(b, writeIDs(tx, iStorage, ids))
+	require.NoError(b, tx.Commit())
+
+	for name, search := range map[string]func(int64, string, string) (string, error){
+		"index": SearchByID,
+		"scan":  rangeScan,
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := search(int64(i)%id+1, iStorage, tStorage); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
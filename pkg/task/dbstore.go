@@ -0,0 +1,365 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"taskTracker/pkg/db"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// ErrImportConflict is returned by ImportJSON when tasks of the JSON storage would
+// overwrite tasks or histories already in the database.
+var ErrImportConflict = errors.New("tasks already in the database")
+
+const (
+	dbTaskPrefix = "task/"
+	dbLogPrefix  = "log/"
+	dbLastIDKey  = "meta/lastID"
+)
+
+// DBStore keeps all tasks in a single embedded database file (see package db).
+// The file is read into memory on open, so a DBStore holds an exclusive lock on
+// <path>.lock until it is closed and other processes wait for it.
+// Every change is written together with its audit event under log/<id>/<n>. A DBStore
+// can be shared by goroutines, e.g. the handlers of serve: mu makes every change,
+// from reading lastID or the old task to writing the batch, one step.
+type DBStore struct {
+	mu    sync.Mutex
+	db    *db.DB
+	lock  *utils.Lock
+	Actor string
+}
+
+// OpenDBStore waits up to timeout for other processes to close the database.
+func OpenDBStore(path string, timeout time.Duration) (*DBStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	l, err := utils.LockExclusive(path+".lock", timeout)
+	if err != nil {
+		return nil, err
+	}
+	d, err := db.Open(path)
+	if err != nil {
+		l.Unlock()
+		return nil, err
+	}
+	return &DBStore{db: d, lock: l, Actor: utils.CurrentUser()}, nil
+}
+
+// Close compacts the file when most of its records are stale and closes it.
+func (s *DBStore) Close() error {
+	defer s.lock.Unlock()
+	if g := s.db.Garbage(); g > 1000 && g > s.db.Len() {
+		if err := s.db.Compact(); err != nil {
+			s.db.Close()
+			return err
+		}
+	}
+	return s.db.Close()
+}
+
+func taskKey(id int64) string {
+	return fmt.Sprintf("%s%020d", dbTaskPrefix, id) // zero padded so keys sort by id
+}
+
+func logPrefix(id int64) string {
+	return fmt.Sprintf("%s%020d/", dbLogPrefix, id)
+}
+
+// eventOp is the op appending ev to the audit log of its task. The caller holds mu.
+func (s *DBStore) eventOp(ev types.Event) (db.Op, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return db.Op{}, err
+	}
+	prefix := logPrefix(ev.TaskID)
+	n := len(s.db.Keys(prefix))
+	return db.Op{Key: fmt.Sprintf("%s%020d", prefix, n), Value: data}, nil
+}
+
+func (s *DBStore) lastID() (int64, error) {
+	v, ok := s.db.Get(dbLastIDKey)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(v), 10, 64)
+}
+
+func (s *DBStore) Create(t *types.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lastID, err := s.lastID()
+	if err != nil {
+		return err
+	}
+	prepareNew(t, s.Actor)
+	t.ID = lastID + 1
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	ev, err := s.eventOp(types.NewEvent(types.OpCreate, s.Actor, nil, t))
+	if err != nil {
+		return err
+	}
+	// the task, the new lastID and the event go into one batch so they can not disagree after a crash
+	return s.db.Batch([]db.Op{
+		{Key: taskKey(t.ID), Value: data},
+		{Key: dbLastIDKey, Value: []byte(strconv.FormatInt(t.ID, 10))},
+		ev,
+	})
+}
+
+func (s *DBStore) Get(id int64) (*types.Task, error) {
+	t, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Deleted() {
+		return nil, os.ErrNotExist
+	}
+	return t, nil
+}
+
+// get reads a task whether it is in the trash or not.
+func (s *DBStore) get(id int64) (*types.Task, error) {
+	v, ok := s.db.Get(taskKey(id))
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	t := &types.Task{}
+	if err := json.Unmarshal(v, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *DBStore) Update(t *types.Task) error {
+	return s.UpdateMany([]*types.Task{t})
+}
+
+// UpdateMany writes the tasks and their events in one batch.
+func (s *DBStore) UpdateMany(ts []*types.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset := keepRevisions(ts)
+	ops := make([]db.Op, 0, 2*len(ts))
+	for _, t := range ts {
+		old, err := s.get(t.ID)
+		if err != nil {
+			reset()
+			return err
+		}
+		if err := prepareUpdate(old, t); err != nil {
+			reset()
+			return err
+		}
+		data, err := json.Marshal(t)
+		if err != nil {
+			reset()
+			return err
+		}
+		ev, err := s.eventOp(types.NewEvent(types.OpUpdate, s.Actor, old, t))
+		if err != nil {
+			reset()
+			return err
+		}
+		ops = append(ops, db.Op{Key: taskKey(t.ID), Value: data}, ev)
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	if err := s.db.Batch(ops); err != nil {
+		reset()
+		return err
+	}
+	return nil
+}
+
+func (s *DBStore) Delete(id int64) error {
+	return s.setDeleted(id, time.Now().Local(), types.OpDelete)
+}
+
+func (s *DBStore) Restore(id int64) error {
+	return s.setDeleted(id, time.Time{}, types.OpRestore)
+}
+
+func (s *DBStore) setDeleted(id int64, at time.Time, op string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	if old.Deleted() == !at.IsZero() {
+		return os.ErrNotExist
+	}
+	updated := *old
+	updated.DeletedAt = at
+	data, err := json.Marshal(&updated)
+	if err != nil {
+		return err
+	}
+	ev, err := s.eventOp(types.NewEvent(op, s.Actor, old, &updated))
+	if err != nil {
+		return err
+	}
+	return s.db.Batch([]db.Op{{Key: taskKey(id), Value: data}, ev})
+}
+
+func (s *DBStore) Purge(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.get(id)
+	if err != nil {
+		return err
+	}
+	if !old.Deleted() {
+		return os.ErrNotExist
+	}
+	ev, err := s.eventOp(types.NewEvent(types.OpPurge, s.Actor, old, nil))
+	if err != nil {
+		return err
+	}
+	return s.db.Batch([]db.Op{{Delete: true, Key: taskKey(id)}, ev})
+}
+
+func (s *DBStore) History(id int64) ([]types.Event, error) {
+	keys := s.db.Keys(logPrefix(id))
+	if len(keys) == 0 {
+		if _, err := s.get(id); err != nil {
+			return nil, err
+		}
+	}
+	events := make([]types.Event, 0, len(keys))
+	for _, k := range keys {
+		v, ok := s.db.Get(k)
+		if !ok {
+			continue
+		}
+		ev := types.Event{}
+		if err := json.Unmarshal(v, &ev); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func (s *DBStore) List() ([]*types.Task, error) {
+	return s.filter(func(t *types.Task) bool { return !t.Deleted() })
+}
+
+func (s *DBStore) Trash() ([]*types.Task, error) {
+	arr, err := s.filter((*types.Task).Deleted)
+	if err != nil {
+		return nil, err
+	}
+	sortByDeleted(arr)
+	return arr, nil
+}
+
+// filter returns the tasks keep accepts ordered by ID.
+func (s *DBStore) filter(keep func(t *types.Task) bool) ([]*types.Task, error) {
+	keys := s.db.Keys(dbTaskPrefix)
+	arr := make([]*types.Task, 0, len(keys))
+	for _, k := range keys {
+		v, ok := s.db.Get(k)
+		if !ok {
+			continue
+		}
+		t := &types.Task{}
+		if err := json.Unmarshal(v, t); err != nil {
+			return nil, err
+		}
+		if keep(t) {
+			arr = append(arr, t)
+		}
+	}
+	return arr, nil
+}
+
+func (s *DBStore) Query(f *types.Filter) ([]*types.Task, error) {
+	arr, err := s.filter(f.MatchTask)
+	if err != nil {
+		return nil, err
+	}
+	sortByCreated(arr)
+	return arr, nil
+}
+
+// ImportJSON copies every task of src, trashed ones included, with its history into dst
+// keeping IDs and timestamps.
+// lastID of dst becomes the larger of src's lastID file and the highest imported ID,
+// so IDs that were issued and later deleted are never handed out again. IDs that are
+// already taken in dst, by a task or the history of a purged one, fail the import with
+// ErrImportConflict before anything is written.
+func ImportJSON(src *JSONStore, dst *DBStore) (int, error) {
+	tasks, err := src.List()
+	if err != nil {
+		return 0, err
+	}
+	trash, err := src.Trash()
+	if err != nil {
+		return 0, err
+	}
+	tasks = append(tasks, trash...)
+	lastID, err := utils.ReadLastID(src.LastIDPath)
+	if err != nil {
+		return 0, err
+	}
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+	dstLast, err := dst.lastID()
+	if err != nil {
+		return 0, err
+	}
+
+	var taken []int64
+	for _, t := range tasks {
+		if _, ok := dst.db.Get(taskKey(t.ID)); ok || len(dst.db.Keys(logPrefix(t.ID))) > 0 {
+			taken = append(taken, t.ID)
+		}
+	}
+	if len(taken) > 0 {
+		return 0, fmt.Errorf("%w: ids %v", ErrImportConflict, taken)
+	}
+
+	ops := make([]db.Op, 0, len(tasks)+1)
+	for _, t := range tasks {
+		data, err := json.Marshal(t)
+		if err != nil {
+			return 0, err
+		}
+		ops = append(ops, db.Op{Key: taskKey(t.ID), Value: data})
+		events, err := src.History(t.ID)
+		if err != nil {
+			return 0, err
+		}
+		for i, ev := range events {
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return 0, err
+			}
+			ops = append(ops, db.Op{Key: fmt.Sprintf("%s%020d", logPrefix(t.ID), i), Value: data})
+		}
+		if t.ID > lastID {
+			lastID = t.ID
+		}
+	}
+	if lastID > dstLast {
+		ops = append(ops, db.Op{Key: dbLastIDKey, Value: []byte(strconv.FormatInt(lastID, 10))})
+	}
+	if err := dst.db.Batch(ops); err != nil {
+		return 0, err
+	}
+	return len(tasks), nil
+}
@@ -0,0 +1,68 @@
+package task
+
+import (
+	"path/filepath"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBStore(t *testing.T) {
+	s, err := OpenDBStore(filepath.Join(t.TempDir(), "tasks.db"), time.Second)
+	require.NoError(t, err)
+	defer s.Close()
+	testStore(t, s)
+}
+
+func TestImportJSON(t *testing.T) {
+	src := newTestJSONStore(t)
+	created := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.Local)
+	for i, desc := range []string{"one", "two", "three"} {
+		at := created.AddDate(0, i, 0) // spread over three month files
+		require.NoError(t, src.Create(&types.Task{Description: desc, CreatedAt: at, UpdateAt: at}))
+	}
+	require.NoError(t, src.Delete(3))
+	require.NoError(t, src.Purge(3))
+
+	dbPath := filepath.Join(t.TempDir(), "tasks.db")
+	dst, err := OpenDBStore(dbPath, time.Second)
+	require.NoError(t, err)
+	n, err := ImportJSON(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, dst.Close())
+
+	dst, err = OpenDBStore(dbPath, time.Second)
+	require.NoError(t, err)
+	defer dst.Close()
+
+	t.Run("same ids and timestamps", func(t *testing.T) {
+		res, err := dst.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "two", res.Description)
+		assert.True(t, res.CreatedAt.Equal(created.AddDate(0, 1, 0)))
+	})
+
+	t.Run("deleted ids are not reused", func(t *testing.T) {
+		last, err := utils.ReadLastID(src.LastIDPath)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), last)
+
+		next := &types.Task{Description: "four"}
+		require.NoError(t, dst.Create(next))
+		assert.Equal(t, int64(4), next.ID)
+	})
+
+	t.Run("importing twice -> conflict", func(t *testing.T) {
+		_, err := ImportJSON(src, dst)
+		require.ErrorIs(t, err, ErrImportConflict)
+		assert.ErrorContains(t, err, "ids [1 2]")
+		res, err := dst.Get(4)
+		require.NoError(t, err)
+		assert.Equal(t, "four", res.Description)
+	})
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+var (
+	// ErrDependency is returned when a task is marked done before the tasks it depends on.
+	ErrDependency = errors.New("task has open dependencies")
+	// ErrCycle is returned for a dependency that would make a task wait for itself.
+	ErrCycle = errors.New("dependency cycle")
+)
+
+// checkDependencies checks the dependencies added to t since old, a nil old for a new
+// task, and whether t may become done. Dependencies on tasks that were deleted since
+// count as done. force skips the check of open dependencies, not the one for cycles.
+func checkDependencies(s Store, old, t *types.Task, force bool) error {
+	for _, dep := range t.DependsOn {
+		if old != nil && slices.Contains(old.DependsOn, dep) {
+			continue
+		}
+		if dep == t.ID {
+			return fmt.Errorf("%w: task %d can not depend on itself", ErrCycle, t.ID)
+		}
+		if _, err := s.Get(dep); err != nil {
+			return fmt.Errorf("dependency %d: %w", dep, err)
+		}
+		if old != nil {
+			if err := checkCycle(s, t.ID, dep); err != nil {
+				return err
+			}
+		}
+	}
+	if force || t.Status != types.StatusDone || (old != nil && old.Status == types.StatusDone) {
+		return nil
+	}
+	open, err := OpenDependencies(s, t)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("%w: task %d waits for %v, force to mark it done anyway", ErrDependency, t.ID, open)
+	}
+	return nil
+}
+
+// checkCycle fails when task id is reachable from dep, that is when dep already waits for id.
+func checkCycle(s Store, id, dep int64) error {
+	seen := map[int64]bool{}
+	queue := []int64{dep}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if cur == id {
+			return fmt.Errorf("%w: task %d already depends on task %d", ErrCycle, dep, id)
+		}
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		t, err := s.Get(cur)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		queue = append(queue, t.DependsOn...)
+	}
+	return nil
+}
+
+// OpenDependencies returns the IDs of the tasks t depends on that are not done yet.
+func OpenDependencies(s Store, t *types.Task) ([]int64, error) {
+	open := make([]int64, 0)
+	for _, dep := range t.DependsOn {
+		d, err := s.Get(dep)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !d.Done() {
+			open = append(open, dep)
+		}
+	}
+	return open, nil
+}
+
+// Depend makes the task id depend on the task on, or drops that dependency when remove is set.
+func Depend(s Store, id, on int64, remove bool) (*types.Task, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.Index(t.DependsOn, on)
+	switch {
+	case remove && i < 0, !remove && i >= 0:
+		return t, nil
+	case remove:
+		t.DependsOn = slices.Delete(t.DependsOn, i, i+1)
+	default:
+		t.DependsOn = append(t.DependsOn, on)
+		slices.Sort(t.DependsOn)
+	}
+	t.UpdateAt = time.Now().Local()
+	if err := Save(s, t, false); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Blocked returns the open tasks that wait for at least one task that is not done, ordered by ID.
+func Blocked(s Store) ([]*types.Task, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*types.Task, len(all))
+	for _, t := range all {
+		byID[t.ID] = t
+	}
+	res := make([]*types.Task, 0)
+	for _, t := range all {
+		if t.Status.Closed() {
+			continue
+		}
+		for _, dep := range t.DependsOn {
+			if d, ok := byID[dep]; ok && !d.Done() {
+				res = append(res, t)
+				break
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"os"
+	"taskTracker/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencies(t *testing.T) {
+	s := NewMemStore()
+	for _, desc := range []string{"design", "build", "ship"} {
+		require.NoError(t, Add(s, &types.Task{Description: desc}))
+	}
+	_, err := Depend(s, 2, 1, false)
+	require.NoError(t, err)
+	res, err := Depend(s, 3, 2, false)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, res.DependsOn)
+
+	t.Run("cycles are rejected", func(t *testing.T) {
+		_, err := Depend(s, 1, 3, false)
+		require.ErrorIs(t, err, ErrCycle)
+		_, err = Depend(s, 1, 1, false)
+		require.ErrorIs(t, err, ErrCycle)
+		_, err = Depend(s, 1, 99, false)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("blocked", func(t *testing.T) {
+		arr, err := Blocked(s)
+		require.NoError(t, err)
+		require.Len(t, arr, 2)
+		assert.Equal(t, int64(2), arr[0].ID)
+		assert.Equal(t, int64(3), arr[1].ID)
+	})
+
+	t.Run("done needs force while dependencies are open", func(t *testing.T) {
+		_, err := Mark(s, 2, types.StatusDone, false)
+		require.ErrorIs(t, err, ErrDependency)
+		_, err = Mark(s, 2, types.StatusDone, true)
+		require.NoError(t, err)
+
+		_, err = Mark(s, 1, types.StatusDone, false)
+		require.NoError(t, err)
+		arr, err := Blocked(s)
+		require.NoError(t, err)
+		assert.Empty(t, arr)
+	})
+
+	t.Run("remove a dependency", func(t *testing.T) {
+		res, err := Depend(s, 3, 2, true)
+		require.NoError(t, err)
+		assert.Empty(t, res.DependsOn)
+	})
+}
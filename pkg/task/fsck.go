@@ -0,0 +1,457 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/search"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+)
+
+// Kinds of problems Fsck finds.
+const (
+	ProblemUnparsable = "unparsable" // a month or index file that does not decode
+	ProblemDuplicate  = "duplicate"  // an ID stored in more than one month file
+	ProblemOrphan     = "orphan"     // a task missing from the ID index or outside the range of its month
+	ProblemStale      = "stale"      // an index entry for a month without tasks, or no ID index at all
+	ProblemOverlap    = "overlap"    // index ranges of two months sharing IDs
+	ProblemLastID     = "last_id"    // lastID behind the highest stored ID, the next create reuses an ID
+)
+
+// Problem is one inconsistency of a JSONStore found by Fsck.
+type Problem struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path,omitempty"`
+	TaskID int64  `json:"task_id,omitempty"`
+	Detail string `json:"detail"`
+	Fixed  bool   `json:"fixed"`
+	Note   bool   `json:"note,omitempty"` // nothing fsck repairs and nothing that hides tasks
+}
+
+func (p Problem) String() string {
+	s := p.Kind + ":"
+	if p.Path != "" {
+		s += " " + p.Path
+	}
+	if p.TaskID != 0 {
+		s += fmt.Sprintf(" task %d", p.TaskID)
+	}
+	s += " " + p.Detail
+	if p.Fixed {
+		s += " (fixed)"
+	}
+	return s
+}
+
+// monthData is a month file read by Fsck.
+type monthData struct {
+	year, month int
+	path        string
+	tasks       map[int64]*types.Task
+	dirty       bool
+}
+
+type monthKey struct{ year, month int }
+
+// Fsck checks the month files against the year index, the ID index and lastID. With fix it repairs
+// what it finds in one journaled write:
+//
+//   - an unparsable month file is moved aside to <file>.corrupt
+//   - of the copies of a duplicate ID the first created keeps it, the others get new IDs
+//   - the year, ID and full-text indexes are rebuilt from the month files, which brings
+//     back orphans and drops stale entries; IDs of purged tasks keep their entry for
+//     their history
+//   - lastID is moved up to the highest stored ID
+//
+// Ranges of months whose IDs interleave, e.g. after a duplicate got a new ID, still
+// overlap after the rebuild. SearchByID uses the ID index and date queries read every
+// month in range, so that does not hide tasks; it is reported as a note. rebuild
+// regenerates the indexes from scratch even when fix is not set.
+func (s *JSONStore) Fsck(fix, rebuild bool) ([]Problem, error) {
+	lock := s.lockShared
+	if fix || rebuild {
+		lock = s.lockExclusive
+	}
+	l, err := lock()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	problems := make([]Problem, 0)
+	unparsable := make(map[string]bool)
+	report := func(p Problem) {
+		if p.Kind == ProblemUnparsable {
+			unparsable[p.Path] = true
+		}
+		problems = append(problems, p)
+	}
+	tx := utils.Begin(s.JournalPath, "fsck")
+
+	months, err := s.scanMonths(fix, tx, report)
+	if err != nil {
+		return nil, err
+	}
+	oldIndex, err := s.readIndexes(fix || rebuild, report)
+	if err != nil {
+		return nil, err
+	}
+	oldIDs, err := s.readIDIndex(fix || rebuild, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// an ID stored twice points to one of its copies, that is reported as a duplicate
+	found := make(map[int64]bool)
+	for _, m := range months {
+		for id := range m.tasks {
+			if loc, ok := oldIDs[id]; ok && loc == (idLocation{m.year, m.month}) {
+				found[id] = true
+			}
+		}
+	}
+
+	var maxID int64
+	for _, m := range months {
+		k := monthKey{m.year, m.month}
+		for id := range m.tasks {
+			maxID = max(maxID, id)
+			var missing []string
+			if r, ok := oldIndex[k]; !ok || !inRange(r, id) {
+				missing = append(missing, "the range of its month")
+			}
+			if loc, ok := oldIDs[id]; oldIDs != nil && !ok {
+				missing = append(missing, "the ID index")
+			} else if ok && loc != (idLocation{m.year, m.month}) && !found[id] {
+				missing = append(missing, "the ID index, which points to "+loc.file(s.TaskDir))
+			}
+			if len(missing) > 0 {
+				report(Problem{Kind: ProblemOrphan, Path: m.path, TaskID: id, Detail: "is not in " + strings.Join(missing, " and "), Fixed: fix || rebuild})
+			}
+		}
+	}
+	lastID, err := utils.ReadLastID(s.LastIDPath)
+	if err != nil {
+		return nil, err
+	}
+	storedMax := maxID
+	storedIndex := monthRanges(months)
+	maxID = max(maxID, lastID)
+	dups, renumbered := s.renumberDuplicates(months, &maxID, fix)
+	for _, p := range dups {
+		report(p)
+	}
+
+	newIndex := monthRanges(months)
+	for k := range oldIndex {
+		path := filepath.Join(s.TaskDir, strconv.Itoa(k.year), fmt.Sprintf("%d.json", k.month))
+		if _, ok := newIndex[k]; !ok && !unparsable[path] {
+			report(Problem{Kind: ProblemStale, Path: path, Detail: "is in the index but holds no tasks", Fixed: fix || rebuild})
+		}
+	}
+	stillOverlap := make(map[[2]monthKey]bool)
+	for _, pair := range overlaps(newIndex) {
+		stillOverlap[pair] = true
+	}
+	for _, pair := range overlaps(oldIndex) {
+		if !stillOverlap[pair] {
+			report(Problem{Kind: ProblemOverlap, Path: s.IndexDir, Detail: overlapDetail(pair, oldIndex), Fixed: fix || rebuild})
+		}
+	}
+	for _, pair := range overlaps(newIndex) {
+		report(Problem{Kind: ProblemOverlap, Path: s.IndexDir, Detail: overlapDetail(pair, newIndex) + ", their task IDs interleave", Note: true})
+	}
+
+	if lastID < storedMax {
+		report(Problem{Kind: ProblemLastID, Path: s.LastIDPath, Detail: fmt.Sprintf("is %d, the highest stored ID is %d", lastID, storedMax), Fixed: fix})
+	}
+
+	if !fix && !rebuild {
+		return problems, nil
+	}
+	if fix {
+		for _, m := range months {
+			if m.dirty {
+				if err := tx.EncodeTasks(m.path, m.tasks); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if lastID < maxID {
+			if err := tx.WriteLastID(maxID, s.LastIDPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !fix {
+		// the renumbered duplicates are not written, so index the files as they are
+		newIndex = storedIndex
+	}
+	if err := s.writeIndexes(tx, newIndex); err != nil {
+		return nil, err
+	}
+	// entries of IDs no month file holds any longer are purged tasks
+	ids := make(map[int64]idLocation)
+	for id, loc := range oldIDs {
+		ids[id] = loc
+	}
+	text := search.New()
+	for _, m := range months {
+		for id, t := range m.tasks {
+			if fix || !renumbered[id] {
+				ids[id] = idLocation{m.year, m.month}
+				text.Add(id, t.Description, t.UpdateAt)
+			}
+		}
+	}
+	if err := writeIDs(tx, s.IndexDir, ids); err != nil {
+		return nil, err
+	}
+	if err := writeTextIndex(tx, s.IndexDir, text); err != nil {
+		return nil, err
+	}
+	return problems, tx.Commit()
+}
+
+// scanMonths reads every month file. Files that do not decode are reported and, with
+// fix, staged to be moved aside.
+func (s *JSONStore) scanMonths(fix bool, tx *utils.Tx, report func(Problem)) ([]*monthData, error) {
+	months := make([]*monthData, 0)
+	err := filepath.WalkDir(s.TaskDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		loc, ok := monthOf(path, d)
+		if !ok {
+			report(Problem{Kind: ProblemUnparsable, Path: path, Detail: "is not named <year>/<month>.json, left as it is", Note: true})
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		m := &monthData{year: loc[0], month: loc[1], path: path, tasks: make(map[int64]*types.Task)}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &m.tasks); err != nil {
+				detail := fmt.Sprintf("does not decode: %v", err)
+				if fix {
+					detail += ", moved to " + filepath.Base(path) + ".corrupt"
+					tx.Write(path+".corrupt", data)
+					tx.Remove(path)
+				}
+				report(Problem{Kind: ProblemUnparsable, Path: path, Detail: detail, Fixed: fix})
+				return nil
+			}
+		}
+		months = append(months, m)
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return months, nil
+}
+
+// readIndexes reads every year index into one map keyed by month. Year files that do
+// not decode are reported, rebuild replaces them.
+func (s *JSONStore) readIndexes(rebuild bool, report func(Problem)) (map[monthKey][]int64, error) {
+	res := make(map[monthKey][]int64)
+	files, err := os.ReadDir(s.IndexDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return res, nil
+		}
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(s.IndexDir, file.Name())
+		year, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		iMap := make(map[int][]int64)
+		if err := utils.DecodeIndex(path, iMap); err != nil {
+			report(Problem{Kind: ProblemUnparsable, Path: path, Detail: fmt.Sprintf("does not decode: %v", err), Fixed: rebuild})
+			continue
+		}
+		for month, r := range iMap {
+			if len(r) > 0 {
+				res[monthKey{year, month}] = r
+			}
+		}
+	}
+	return res, nil
+}
+
+// readIDIndex reads the ID index as it is on disk. A missing or unparsable one is
+// reported once, not for every task, and nil is returned; rebuild replaces it.
+func (s *JSONStore) readIDIndex(rebuild bool, report func(Problem)) (map[int64]idLocation, error) {
+	fPath := idIndexFile(s.IndexDir)
+	data, err := os.ReadFile(fPath)
+	if errors.Is(err, os.ErrNotExist) {
+		report(Problem{Kind: ProblemStale, Path: fPath, Detail: "is missing, lookups by ID scan every month file", Fixed: rebuild})
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids, err := decodeIDs(data)
+	if err != nil {
+		report(Problem{Kind: ProblemUnparsable, Path: fPath, Detail: fmt.Sprintf("does not decode: %v", err), Fixed: rebuild})
+		return nil, nil
+	}
+	return ids, nil
+}
+
+// renumberDuplicates finds IDs stored in several month files. The first created copy
+// keeps the ID, the others get IDs after maxID. Without fix that only happens in memory,
+// so the rest of the check sees the storage as the fix would leave it. It returns the
+// problems and the new IDs.
+func (s *JSONStore) renumberDuplicates(months []*monthData, maxID *int64, fix bool) ([]Problem, map[int64]bool) {
+	where := make(map[int64][]*monthData)
+	for _, m := range months {
+		for id := range m.tasks {
+			where[id] = append(where[id], m)
+		}
+	}
+	ids := make([]int64, 0)
+	for id, ms := range where {
+		if len(ms) > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	problems := make([]Problem, 0)
+	renumbered := make(map[int64]bool)
+	for _, id := range ids {
+		ms := where[id]
+		sort.SliceStable(ms, func(i, j int) bool { return ms[i].tasks[id].CreatedAt.Before(ms[j].tasks[id].CreatedAt) })
+		for _, m := range ms[1:] {
+			*maxID++
+			t := m.tasks[id]
+			delete(m.tasks, id)
+			t.ID = *maxID
+			m.tasks[t.ID] = t
+			m.dirty = true
+			renumbered[t.ID] = true
+			detail := fmt.Sprintf("is also stored in %s, -fix makes it task %d", ms[0].path, t.ID)
+			if fix {
+				detail = fmt.Sprintf("is also stored in %s, now task %d", ms[0].path, t.ID)
+			}
+			problems = append(problems, Problem{Kind: ProblemDuplicate, Path: m.path, TaskID: id, Detail: detail, Fixed: fix})
+		}
+	}
+	return problems, renumbered
+}
+
+// writeIndexes stages the year index files for index and removes the ones of years
+// without tasks.
+func (s *JSONStore) writeIndexes(tx *utils.Tx, index map[monthKey][]int64) error {
+	if err := os.MkdirAll(s.IndexDir, 0755); err != nil {
+		return err
+	}
+	years := make(map[int]map[int][]int64)
+	for k, r := range index {
+		if years[k.year] == nil {
+			years[k.year] = make(map[int][]int64)
+		}
+		years[k.year][k.month] = r
+	}
+	files, err := os.ReadDir(s.IndexDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		year, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".json"))
+		if file.IsDir() || err != nil || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		if _, ok := years[year]; !ok {
+			tx.Remove(filepath.Join(s.IndexDir, file.Name()))
+		}
+	}
+	for year, iMap := range years {
+		if err := tx.EncodeIndex(filepath.Join(s.IndexDir, fmt.Sprintf("%v.json", year)), iMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monthRanges is the index of months as it follows from their tasks.
+func monthRanges(months []*monthData) map[monthKey][]int64 {
+	res := make(map[monthKey][]int64)
+	for _, m := range months {
+		if r := idRange(m.tasks); r != nil {
+			res[monthKey{m.year, m.month}] = r
+		}
+	}
+	return res
+}
+
+// idRange is the index entry of a month: its first and last ID, or the only one.
+func idRange(tasks map[int64]*types.Task) []int64 {
+	if len(tasks) == 0 {
+		return nil
+	}
+	var lo, hi int64
+	for id := range tasks {
+		if lo == 0 || id < lo {
+			lo = id
+		}
+		hi = max(hi, id)
+	}
+	if lo == hi {
+		return []int64{lo}
+	}
+	return []int64{lo, hi}
+}
+
+func inRange(r []int64, id int64) bool {
+	if len(r) < 2 {
+		return len(r) == 1 && r[0] == id
+	}
+	return r[0] <= id && id <= r[1]
+}
+
+// overlaps returns the pairs of months whose ranges share IDs, earlier month first.
+func overlaps(index map[monthKey][]int64) [][2]monthKey {
+	keys := make([]monthKey, 0, len(index))
+	for k := range index {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].year != keys[j].year {
+			return keys[i].year < keys[j].year
+		}
+		return keys[i].month < keys[j].month
+	})
+	res := make([][2]monthKey, 0)
+	for i, a := range keys {
+		for _, b := range keys[i+1:] {
+			ra, rb := index[a], index[b]
+			if ra[0] <= rb[len(rb)-1] && rb[0] <= ra[len(ra)-1] {
+				res = append(res, [2]monthKey{a, b})
+			}
+		}
+	}
+	return res
+}
+
+func overlapDetail(pair [2]monthKey, index map[monthKey][]int64) string {
+	a, b := pair[0], pair[1]
+	return fmt.Sprintf("range %v of %d/%d overlaps %v of %d/%d", index[a], a.year, a.month, index[b], b.year, b.month)
+}
@@ -0,0 +1,102 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func kinds(problems []Problem) map[string]int {
+	res := make(map[string]int)
+	for _, p := range problems {
+		res[p.Kind]++
+	}
+	return res
+}
+
+func TestJSONStoreFsck(t *testing.T) {
+	s := newTestJSONStore(t)
+	for _, desc := range []string{"one", "two", "three"} {
+		require.NoError(t, s.Create(&types.Task{Description: desc}))
+	}
+	problems, err := s.Fsck(false, false)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	t.Run("lost index", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(s.IndexDir))
+		got, err := s.Get(2)
+		require.NoError(t, err, "lookups by ID fall back to the month files")
+		assert.Equal(t, "two", got.Description)
+		arr, err := s.Query(types.NewFilter())
+		require.NoError(t, err)
+		assert.Empty(t, arr, "date queries need the year index")
+
+		problems, err := s.Fsck(false, false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{ProblemOrphan: 3, ProblemStale: 1}, kinds(problems))
+		assert.False(t, problems[0].Fixed)
+
+		problems, err = s.Fsck(false, true)
+		require.NoError(t, err)
+		assert.True(t, problems[0].Fixed)
+		arr, err = s.Query(types.NewFilter())
+		require.NoError(t, err)
+		assert.Len(t, arr, 3)
+		problems, err = s.Fsck(false, false)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("hand edits", func(t *testing.T) {
+		require.NoError(t, utils.WriteLastID(1, s.LastIDPath))
+		dup := &types.Task{ID: 2, Description: "copy of two", Status: types.StatusTodo, CreatedAt: time.Now().Add(time.Hour)}
+		require.NoError(t, os.MkdirAll(filepath.Join(s.TaskDir, "2020"), 0755))
+		require.NoError(t, utils.EncodeTasks(filepath.Join(s.TaskDir, "2020", "1.json"), map[int64]*types.Task{2: dup}))
+		broken := filepath.Join(s.TaskDir, "2020", "2.json")
+		require.NoError(t, os.WriteFile(broken, []byte("{not json"), 0644))
+
+		problems, err := s.Fsck(false, false)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{ProblemUnparsable: 1, ProblemOrphan: 1, ProblemDuplicate: 1, ProblemLastID: 1}, kinds(problems))
+
+		problems, err = s.Fsck(true, false)
+		require.NoError(t, err)
+		for _, p := range problems {
+			assert.True(t, p.Fixed, p.String())
+		}
+		_, err = os.Stat(broken + ".corrupt")
+		require.NoError(t, err)
+		lastID, err := utils.ReadLastID(s.LastIDPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), lastID)
+
+		got, err := s.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "two", got.Description, "the first created copy keeps the id")
+		got, err = s.Get(4)
+		require.NoError(t, err)
+		assert.Equal(t, "copy of two", got.Description)
+
+		problems, err = s.Fsck(false, false)
+		require.NoError(t, err)
+		assert.Empty(t, problems)
+	})
+
+	t.Run("interleaved ids are found in both months", func(t *testing.T) {
+		old := &types.Task{ID: 9, Description: "late", Status: types.StatusTodo, CreatedAt: time.Date(2020, 1, 5, 0, 0, 0, 0, time.Local)}
+		require.NoError(t, utils.EncodeTasks(filepath.Join(s.TaskDir, "2020", "1.json"), map[int64]*types.Task{1: old, 9: old}))
+		problems, err := s.Fsck(true, false)
+		require.NoError(t, err)
+		assert.Contains(t, kinds(problems), ProblemOverlap)
+		got, err := s.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "two", got.Description)
+	})
+}
@@ -0,0 +1,230 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+)
+
+// idIndexName is the file of the index directory mapping every ID ever issued to the
+// month file it was created in. Line N holds <year><month> of task N as "202605", or
+// "000000" for an ID no task has, so a lookup reads one record at a known offset. The
+// year files next to it only keep the first and last ID of every month, which is what
+// date queries need.
+const idIndexName = "ids.txt"
+
+const idRecordSize = len("202605\n")
+
+// idLocation is the year and month of a month file.
+type idLocation [2]int
+
+func (l idLocation) file(tStorage string) string {
+	return filepath.Join(tStorage, strconv.Itoa(l[0]), fmt.Sprintf("%d.json", l[1]))
+}
+
+func idIndexFile(iStorage string) string {
+	return filepath.Join(iStorage, idIndexName)
+}
+
+// lookupID reads the record of id from the ID index. Storage written before the ID
+// index existed has none, then the month files are searched.
+func lookupID(id int64, iStorage, tStorage string) (idLocation, error) {
+	f, err := os.Open(idIndexFile(iStorage))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return idLocation{}, err
+		}
+		ids, err := scanIDs(tStorage)
+		if err != nil {
+			return idLocation{}, err
+		}
+		loc, ok := ids[id]
+		if !ok {
+			return idLocation{}, os.ErrNotExist
+		}
+		return loc, nil
+	}
+	defer f.Close()
+	if id < 1 {
+		return idLocation{}, os.ErrNotExist
+	}
+	rec := make([]byte, idRecordSize)
+	if _, err := f.ReadAt(rec, (id-1)*int64(idRecordSize)); err != nil {
+		if errors.Is(err, io.EOF) {
+			return idLocation{}, os.ErrNotExist
+		}
+		return idLocation{}, err
+	}
+	loc, ok, err := parseIDRecord(rec)
+	if err != nil {
+		return idLocation{}, fmt.Errorf("%s: task %d: %w", idIndexFile(iStorage), id, err)
+	}
+	if !ok {
+		return idLocation{}, os.ErrNotExist
+	}
+	return loc, nil
+}
+
+// parseIDRecord parses one line of the ID index. ok is false for an unused ID.
+func parseIDRecord(rec []byte) (idLocation, bool, error) {
+	if len(rec) != idRecordSize || rec[idRecordSize-1] != '\n' {
+		return idLocation{}, false, errors.New("broken record")
+	}
+	n, err := strconv.Atoi(string(rec[:idRecordSize-1]))
+	if err != nil {
+		return idLocation{}, false, fmt.Errorf("broken record %q", rec[:idRecordSize-1])
+	}
+	if n == 0 {
+		return idLocation{}, false, nil
+	}
+	loc := idLocation{n / 100, n % 100}
+	if loc[1] < 1 || loc[1] > 12 {
+		return idLocation{}, false, fmt.Errorf("broken record %q", rec[:idRecordSize-1])
+	}
+	return loc, true, nil
+}
+
+// decodeIDs parses a whole ID index.
+func decodeIDs(data []byte) (map[int64]idLocation, error) {
+	if len(data)%idRecordSize != 0 {
+		return nil, fmt.Errorf("size %d is not a multiple of %d", len(data), idRecordSize)
+	}
+	ids := make(map[int64]idLocation, len(data)/idRecordSize)
+	for i := 0; i < len(data); i += idRecordSize {
+		loc, ok, err := parseIDRecord(data[i : i+idRecordSize])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i/idRecordSize+1, err)
+		}
+		if ok {
+			ids[int64(i/idRecordSize)+1] = loc
+		}
+	}
+	return ids, nil
+}
+
+// readIDs reads the whole ID index, preferring what tx has already staged for it.
+// Without an ID index it is built from the month files and saved by the next write.
+func readIDs(tx *utils.Tx, iStorage, tStorage string) (map[int64]idLocation, error) {
+	fPath := idIndexFile(iStorage)
+	data, err := os.ReadFile(fPath)
+	if f, ok := tx.Pending(fPath); ok {
+		if f.Remove {
+			return make(map[int64]idLocation), nil
+		}
+		data, err = f.Content(data), nil
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return scanIDs(tStorage)
+		}
+		return nil, err
+	}
+	ids, err := decodeIDs(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fPath, err)
+	}
+	return ids, nil
+}
+
+// setID stages the record of id in the ID index, and records of unused IDs for the ones
+// missing before it, without rewriting the records of other IDs. The zero location
+// marks id unused again. Without an ID index the whole of it is built and written.
+func setID(tx *utils.Tx, iStorage, tStorage string, id int64, loc idLocation) error {
+	fPath := idIndexFile(iStorage)
+	info, err := os.Stat(fPath)
+	if errors.Is(err, os.ErrNotExist) {
+		ids, err := readIDs(tx, iStorage, tStorage)
+		if err != nil {
+			return err
+		}
+		ids[id] = loc
+		return writeIDs(tx, iStorage, ids)
+	}
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if f, ok := tx.Pending(fPath); ok {
+		size = int64(len(f.Content(make([]byte, size))))
+	}
+	if size%int64(idRecordSize) != 0 {
+		return fmt.Errorf("%s: size %d is not a multiple of %d", fPath, size, idRecordSize)
+	}
+	off := (id - 1) * int64(idRecordSize)
+	var data []byte
+	for n := size; n < off; n += int64(idRecordSize) {
+		data = fmt.Appendf(data, "%06d\n", 0)
+	}
+	data = fmt.Appendf(data, "%04d%02d\n", loc[0], loc[1])
+	return tx.WriteAt(fPath, min(size, off), data)
+}
+
+// writeIDs stages the ID index.
+func writeIDs(tx *utils.Tx, iStorage string, ids map[int64]idLocation) error {
+	var n int64
+	for id := range ids {
+		n = max(n, id)
+	}
+	data := make([]byte, 0, n*int64(idRecordSize))
+	for id := int64(1); id <= n; id++ {
+		loc := ids[id]
+		data = fmt.Appendf(data, "%04d%02d\n", loc[0], loc[1])
+	}
+	tx.Write(idIndexFile(iStorage), data)
+	return nil
+}
+
+// scanIDs builds the ID index from the month files. Month files that do not decode are
+// skipped, Fsck reports them.
+func scanIDs(tStorage string) (map[int64]idLocation, error) {
+	ids := make(map[int64]idLocation)
+	err := filepath.WalkDir(tStorage, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		loc, ok := monthOf(path, d)
+		if !ok {
+			return nil
+		}
+		tMap := make(map[int64]*types.Task)
+		if err := utils.DecodeTasks(path, tMap); err != nil {
+			return nil
+		}
+		for id := range tMap {
+			ids[id] = loc
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// monthOf parses the year and month out of the path of a month file, <year>/<month>.json.
+func monthOf(path string, d os.DirEntry) (idLocation, bool) {
+	if d.IsDir() {
+		return idLocation{}, false
+	}
+	return monthOfFile(path)
+}
+
+// monthOfFile is monthOf for a path known to be a file.
+func monthOfFile(path string) (idLocation, bool) {
+	name := filepath.Base(path)
+	if !strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".") {
+		return idLocation{}, false
+	}
+	year, yErr := strconv.Atoi(filepath.Base(filepath.Dir(path)))
+	month, mErr := strconv.Atoi(strings.TrimSuffix(name, ".json"))
+	if yErr != nil || mErr != nil || month < 1 || month > 12 {
+		return idLocation{}, false
+	}
+	return idLocation{year, month}, true
+}
@@ -0,0 +1,343 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// JSONStore keeps tasks in <TaskDir>/<year>/<month>.json files, the first and last ID
+// of every month in <IndexDir>/<year>.json, the month of every ID in <IndexDir>/ids.txt,
+// a full-text index of the descriptions in <IndexDir>/search.json and the last issued
+// ID in LastIDPath. Every mutation goes through a journal (see utils.Tx) so they
+// always agree.
+// The same transaction appends an event done by Actor to the audit log in LogDir and
+// keeps the last UndoLimit changes in UndoPath for Undo and Redo, which only take back
+// changes made by Actor and, when UndoProject is set, of tasks in it. Templates of recurring
+// tasks are kept in RecurPath, see Materialize, and the list of projects in ProjectsPath.
+//
+// Writers hold an exclusive lock on LockPath for the whole read-modify-write, readers a
+// shared one, so several processes can use the same storage. A lock that can not be
+// taken within LockTimeout fails with utils.ErrStorageBusy.
+type JSONStore struct {
+	TaskDir      string
+	IndexDir     string
+	LastIDPath   string
+	LogDir       string
+	UndoPath     string
+	UndoLimit    int
+	RecurPath    string
+	ProjectsPath string
+	JournalPath  string
+	LockPath     string
+	LockTimeout  time.Duration
+	Actor        string
+	UndoProject  string
+}
+
+const DefaultLockTimeout = 5 * time.Second
+
+// NewJSONStore keeps the journal, the lock file, the undo history, the recurring task
+// templates, the projects and the logs directory next to the lastID file. Events are
+// recorded as done by the current user.
+func NewJSONStore(taskDir, indexDir, lastIDPath string) *JSONStore {
+	dir := filepath.Dir(lastIDPath)
+	return &JSONStore{
+		TaskDir:      taskDir,
+		IndexDir:     indexDir,
+		LastIDPath:   lastIDPath,
+		LogDir:       filepath.Join(dir, "logs"),
+		UndoPath:     filepath.Join(dir, "undo.json"),
+		UndoLimit:    DefaultUndoLimit,
+		RecurPath:    filepath.Join(dir, "recurring.json"),
+		ProjectsPath: filepath.Join(dir, "projects.json"),
+		JournalPath:  filepath.Join(dir, "journal.json"),
+		LockPath:     filepath.Join(dir, "lock"),
+		LockTimeout:  DefaultLockTimeout,
+		Actor:        utils.CurrentUser(),
+	}
+}
+
+// NewJSONStoreIn lays the storage out below root: tasks, index and logs directories and
+// the lastID file next to them.
+func NewJSONStoreIn(root string) *JSONStore {
+	s := NewJSONStore(filepath.Join(root, "tasks"), filepath.Join(root, "index"), filepath.Join(root, "lastID.json"))
+	s.LogDir = filepath.Join(root, "logs")
+	return s
+}
+
+// lockShared takes the readers' lock. A change that a crashed writer left in the
+// journal is finished first, under the exclusive lock, so readers never see half of it.
+func (s *JSONStore) lockShared() (*utils.Lock, error) {
+	for {
+		l, err := utils.LockShared(s.LockPath, s.LockTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(s.JournalPath); errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		l.Unlock()
+		if l, err = s.lockExclusive(); err != nil {
+			return nil, err
+		}
+		l.Unlock()
+	}
+}
+
+// lockExclusive takes the writers' lock and finishes a change that a crashed writer
+// left in the journal, so the next one does not start from half of it.
+func (s *JSONStore) lockExclusive() (*utils.Lock, error) {
+	l, _, err := s.lockRecovered()
+	return l, err
+}
+
+func (s *JSONStore) lockRecovered() (*utils.Lock, bool, error) {
+	l, err := utils.LockExclusive(s.LockPath, s.LockTimeout)
+	if err != nil {
+		return nil, false, err
+	}
+	recovered, err := utils.Recover(s.JournalPath)
+	if err != nil {
+		l.Unlock()
+		return nil, false, err
+	}
+	return l, recovered, nil
+}
+
+// Recover rolls forward a mutation interrupted by a crash. Every lock taken by the store
+// does that too, calling it on startup only reports whether there was one.
+func (s *JSONStore) Recover() (bool, error) {
+	l, recovered, err := s.lockRecovered()
+	if err != nil {
+		return false, err
+	}
+	l.Unlock()
+	return recovered, nil
+}
+
+func (s *JSONStore) Create(t *types.Task) error {
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	lastID, err := utils.ReadLastID(s.LastIDPath)
+	if err != nil {
+		return err
+	}
+	prepareNew(t, s.Actor)
+	t.ID = lastID + 1
+	tx := utils.Begin(s.JournalPath, "create")
+	if err := CreateTask(tx, t, s.TaskDir, s.IndexDir, s.LastIDPath); err != nil {
+		return err
+	}
+	return s.commit(tx, monthFile(s.TaskDir, t.CreatedAt), types.NewEvent(types.OpCreate, s.Actor, nil, t))
+}
+
+func (s *JSONStore) Get(id int64) (*types.Task, error) {
+	l, err := s.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	targetFile, err := SearchByID(id, s.IndexDir, s.TaskDir)
+	if err != nil {
+		return nil, err
+	}
+	return GetByID(id, targetFile)
+}
+
+func (s *JSONStore) Update(t *types.Task) error {
+	return s.UpdateMany([]*types.Task{t})
+}
+
+// UpdateMany replaces the tasks in one journaled write, which Undo takes back as one step.
+func (s *JSONStore) UpdateMany(ts []*types.Task) error {
+	if len(ts) == 0 {
+		return nil
+	}
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	reset := keepRevisions(ts)
+	tx := utils.Begin(s.JournalPath, "update")
+	files := make([]string, 0, len(ts))
+	evs := make([]types.Event, 0, len(ts))
+	for _, t := range ts {
+		targetFile, err := SearchByID(t.ID, s.IndexDir, s.TaskDir)
+		if err != nil {
+			reset()
+			return err
+		}
+		old, err := Update(tx, t, targetFile, s.IndexDir, s.TaskDir)
+		if err != nil {
+			reset()
+			return err
+		}
+		files = append(files, targetFile)
+		evs = append(evs, types.NewEvent(types.OpUpdate, s.Actor, old, t))
+	}
+	if err := s.commitMany(tx, files, evs); err != nil {
+		reset()
+		return err
+	}
+	return nil
+}
+
+func (s *JSONStore) Delete(id int64) error {
+	return s.setDeleted(id, time.Now().Local(), types.OpDelete)
+}
+
+func (s *JSONStore) Restore(id int64) error {
+	return s.setDeleted(id, time.Time{}, types.OpRestore)
+}
+
+func (s *JSONStore) setDeleted(id int64, at time.Time, op string) error {
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	targetFile, err := SearchByID(id, s.IndexDir, s.TaskDir)
+	if err != nil {
+		return err
+	}
+	tx := utils.Begin(s.JournalPath, op)
+	old, updated, err := SetDeleted(tx, id, targetFile, at)
+	if err != nil {
+		return err
+	}
+	return s.commit(tx, targetFile, types.NewEvent(op, s.Actor, old, updated))
+}
+
+func (s *JSONStore) Purge(id int64) error {
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	targetFile, err := SearchByID(id, s.IndexDir, s.TaskDir)
+	if err != nil {
+		return err
+	}
+	tx := utils.Begin(s.JournalPath, types.OpPurge)
+	old, err := Delete(tx, id, targetFile, s.IndexDir, s.TaskDir)
+	if err != nil {
+		return err
+	}
+	if !old.Deleted() {
+		return os.ErrNotExist
+	}
+	return s.commit(tx, targetFile, types.NewEvent(types.OpPurge, s.Actor, old, nil))
+}
+
+func (s *JSONStore) List() ([]*types.Task, error) {
+	arr, err := s.walk(func(t *types.Task) bool { return !t.Deleted() })
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(arr, func(i, j int) bool { return arr[i].ID < arr[j].ID })
+	return arr, nil
+}
+
+func (s *JSONStore) Trash() ([]*types.Task, error) {
+	arr, err := s.walk((*types.Task).Deleted)
+	if err != nil {
+		return nil, err
+	}
+	sortByDeleted(arr)
+	return arr, nil
+}
+
+// walk reads every month file and returns the tasks keep accepts.
+func (s *JSONStore) walk(keep func(t *types.Task) bool) ([]*types.Task, error) {
+	l, err := s.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+	return s.scan(keep)
+}
+
+// scan is walk for a caller that holds the lock.
+func (s *JSONStore) scan(keep func(t *types.Task) bool) ([]*types.Task, error) {
+	arr := make([]*types.Task, 0)
+	err := filepath.WalkDir(s.TaskDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+		tMap := make(map[int64]*types.Task)
+		if err := utils.DecodeTasks(path, tMap); err != nil {
+			return err
+		}
+		for _, t := range tMap {
+			if keep(t) {
+				arr = append(arr, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
+
+func (s *JSONStore) Query(f *types.Filter) ([]*types.Task, error) {
+	l, err := s.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	arr, err := GetByDate(s.TaskDir, s.IndexDir, f)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*types.Task{}, nil
+		}
+		return nil, err
+	}
+	return arr, nil
+}
+
+func (s *JSONStore) History(id int64) ([]types.Event, error) {
+	l, err := s.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	targetFile, err := SearchByID(id, s.IndexDir, s.TaskDir)
+	if err != nil {
+		return nil, err
+	}
+	fPath, err := logFile(s.TaskDir, s.LogDir, targetFile)
+	if err != nil {
+		return nil, err
+	}
+	events, err := utils.DecodeEvents(fPath, id)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(events) == 0 {
+		// tasks from before the audit log have none
+		return []types.Event{}, nil
+	}
+	return events, nil
+}
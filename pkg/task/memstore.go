@@ -0,0 +1,151 @@
+package task
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// MemStore keeps tasks in memory. It is meant for tests and short-lived tools.
+type MemStore struct {
+	mu     sync.RWMutex
+	tasks  map[int64]types.Task
+	events map[int64][]types.Event
+	lastID int64
+	Actor  string
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{tasks: make(map[int64]types.Task), events: make(map[int64][]types.Event), Actor: utils.CurrentUser()}
+}
+
+func (s *MemStore) Create(t *types.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prepareNew(t, s.Actor)
+	s.lastID++
+	t.ID = s.lastID
+	s.tasks[t.ID] = *t
+	s.record(types.NewEvent(types.OpCreate, s.Actor, nil, t))
+	return nil
+}
+
+func (s *MemStore) Get(id int64) (*types.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tasks[id]
+	if !ok || t.Deleted() {
+		return nil, os.ErrNotExist
+	}
+	return &t, nil
+}
+
+func (s *MemStore) Update(t *types.Task) error {
+	return s.UpdateMany([]*types.Task{t})
+}
+
+func (s *MemStore) UpdateMany(ts []*types.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reset := keepRevisions(ts)
+	olds := make([]types.Task, len(ts))
+	for i, t := range ts {
+		old, ok := s.tasks[t.ID]
+		if !ok {
+			reset()
+			return os.ErrNotExist
+		}
+		if err := prepareUpdate(&old, t); err != nil {
+			reset()
+			return err
+		}
+		olds[i] = old
+	}
+	for i, t := range ts {
+		s.tasks[t.ID] = *t
+		s.record(types.NewEvent(types.OpUpdate, s.Actor, &olds[i], t))
+	}
+	return nil
+}
+
+func (s *MemStore) Delete(id int64) error {
+	return s.setDeleted(id, time.Now().Local(), types.OpDelete)
+}
+
+func (s *MemStore) Restore(id int64) error {
+	return s.setDeleted(id, time.Time{}, types.OpRestore)
+}
+
+func (s *MemStore) setDeleted(id int64, at time.Time, op string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.tasks[id]
+	if !ok || old.Deleted() == !at.IsZero() {
+		return os.ErrNotExist
+	}
+	updated := old
+	updated.DeletedAt = at
+	s.tasks[id] = updated
+	s.record(types.NewEvent(op, s.Actor, &old, &updated))
+	return nil
+}
+
+func (s *MemStore) Purge(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.tasks[id]
+	if !ok || !old.Deleted() {
+		return os.ErrNotExist
+	}
+	delete(s.tasks, id)
+	s.record(types.NewEvent(types.OpPurge, s.Actor, &old, nil))
+	return nil
+}
+
+// record has to be called with s.mu held.
+func (s *MemStore) record(ev types.Event) {
+	s.events[ev.TaskID] = append(s.events[ev.TaskID], ev)
+}
+
+func (s *MemStore) History(id int64) ([]types.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	events, ok := s.events[id]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return append([]types.Event{}, events...), nil
+}
+
+func (s *MemStore) List() ([]*types.Task, error) {
+	return s.filter(func(t *types.Task) bool { return !t.Deleted() }), nil
+}
+
+func (s *MemStore) Trash() ([]*types.Task, error) {
+	arr := s.filter((*types.Task).Deleted)
+	sortByDeleted(arr)
+	return arr, nil
+}
+
+func (s *MemStore) Query(f *types.Filter) ([]*types.Task, error) {
+	arr := s.filter(f.MatchTask)
+	sortByCreated(arr)
+	return arr, nil
+}
+
+func (s *MemStore) filter(keep func(*types.Task) bool) []*types.Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	arr := make([]*types.Task, 0)
+	for _, t := range s.tasks {
+		t := t
+		if keep(&t) {
+			arr = append(arr, &t)
+		}
+	}
+	sort.Slice(arr, func(i, j int) bool { return arr[i].ID < arr[j].ID })
+	return arr
+}
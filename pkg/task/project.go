@@ -0,0 +1,382 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+var (
+	// ErrProjectExists is returned when a project is added twice.
+	ErrProjectExists = errors.New("project exists")
+	// ErrProjectInUse is returned when a project that still has tasks or recurring task
+	// templates is removed.
+	ErrProjectInUse = errors.New("project in use")
+)
+
+// projectFile is kept in JSONStore.ProjectsPath.
+type projectFile struct {
+	Projects []*types.Project `json:"projects"`
+}
+
+func (s *JSONStore) readProjects() (*projectFile, error) {
+	f := &projectFile{Projects: make([]*types.Project, 0)}
+	data, err := os.ReadFile(s.ProjectsPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return f, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *JSONStore) writeProjects(tx *utils.Tx, f *projectFile) error {
+	sort.Slice(f.Projects, func(i, j int) bool { return f.Projects[i].Name < f.Projects[j].Name })
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tx.Write(s.ProjectsPath, data)
+	return nil
+}
+
+// AddProject checks the name of p and saves it. The list of projects is kept with the
+// JSON storage, also when the tasks are kept in a database.
+func (s *JSONStore) AddProject(p *types.Project) error {
+	name, err := types.ParseProjectName(p.Name)
+	if err != nil {
+		return err
+	}
+	p.Name = name
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	f, err := s.readProjects()
+	if err != nil {
+		return err
+	}
+	for _, other := range f.Projects {
+		if other.Name == p.Name {
+			return fmt.Errorf("%w: %s", ErrProjectExists, p.Name)
+		}
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now().Local()
+	}
+	f.Projects = append(f.Projects, p)
+	tx := utils.Begin(s.JournalPath, "add project")
+	if err := s.writeProjects(tx, f); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Projects returns the projects ordered by name.
+func (s *JSONStore) Projects() ([]*types.Project, error) {
+	l, err := s.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	f, err := s.readProjects()
+	if err != nil {
+		return nil, err
+	}
+	return f.Projects, nil
+}
+
+// Project returns the project with the given name.
+func (s *JSONStore) Project(name string) (*types.Project, error) {
+	projects, err := s.Projects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, &NotFoundError{What: fmt.Sprintf("project %q", name)}
+}
+
+// RemoveProject drops a project from the list of projects of reg. It fails with
+// ErrProjectInUse while tasks of s, trashed ones included, or recurring task templates
+// of reg are still in it. They are counted under the lock of reg, so a task created in
+// the meantime can not be left in a removed project when s is reg.
+func RemoveProject(reg *JSONStore, s Store, name string) error {
+	l, err := reg.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+	f, err := reg.readProjects()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(f.Projects, func(p *types.Project) bool { return p.Name == name }) {
+		return &NotFoundError{What: fmt.Sprintf("project %q", name)}
+	}
+
+	var tasks []*types.Task
+	if unwrap(s) == Store(reg) {
+		// reg's lock is held, the locked List and Trash would wait for it
+		tasks, err = reg.scan(func(t *types.Task) bool { return t.Project == name })
+		if err != nil {
+			return err
+		}
+	} else {
+		all, err := s.List()
+		if err != nil {
+			return err
+		}
+		trashed, err := s.Trash()
+		if err != nil {
+			return err
+		}
+		for _, t := range append(all, trashed...) {
+			if t.Project == name {
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	if len(tasks) > 0 {
+		return fmt.Errorf("%w: move or purge the %d tasks of project %s first", ErrProjectInUse, len(tasks), name)
+	}
+	tf, err := reg.readTemplates()
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, tpl := range tf.Templates {
+		if tpl.Project == name {
+			n++
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: remove the %d recurring task templates of project %s first", ErrProjectInUse, n, name)
+	}
+
+	for i, p := range f.Projects {
+		if p.Name == name {
+			f.Projects = append(f.Projects[:i], f.Projects[i+1:]...)
+			tx := utils.Begin(reg.JournalPath, "remove project")
+			if err := reg.writeProjects(tx, f); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}
+	}
+	return &NotFoundError{What: fmt.Sprintf("project %q", name)}
+}
+
+// ProjectProgress counts the tasks of every project in tasks, keyed by project name;
+// tasks without a project are counted under "". Cancelled tasks are left out.
+func ProjectProgress(tasks []*types.Task) map[string]types.Progress {
+	res := make(map[string]types.Progress)
+	for _, t := range tasks {
+		if t.Status == types.StatusCancelled {
+			continue
+		}
+		p := res[t.Project]
+		p.Total++
+		if t.Done() {
+			p.Done++
+		}
+		res[t.Project] = p
+	}
+	return res
+}
+
+// Move puts the task with the given id and all its subtasks into project, "" takes them
+// out of any project. A subtask only moves with its parent and fails with ErrParent. The
+// tasks are updated at once through UpdateMany, so either all of them move or none. It
+// returns the IDs of the moved tasks.
+func Move(s Store, id int64, project string) ([]int64, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.ParentID != 0 {
+		return nil, fmt.Errorf("%w: task %d is a subtask of task %d, move that one", ErrParent, id, t.ParentID)
+	}
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int64][]*types.Task)
+	for _, c := range all {
+		if c.ParentID != 0 {
+			children[c.ParentID] = append(children[c.ParentID], c)
+		}
+	}
+
+	var changed []*types.Task
+	now := time.Now().Local()
+	seen := map[int64]bool{}
+	queue := []*types.Task{t}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		queue = append(queue, children[t.ID]...)
+		if t.Project == project {
+			continue
+		}
+		t.Project = project
+		t.UpdateAt = now
+		changed = append(changed, t)
+	}
+	if err := s.UpdateMany(changed); err != nil {
+		return nil, err
+	}
+	moved := make([]int64, 0, len(changed))
+	for _, t := range changed {
+		moved = append(moved, t.ID)
+	}
+	return moved, nil
+}
+
+// ProjectStore limits a Store to the tasks of one project: Create puts new tasks into
+// it and the tasks of other projects are not found, as if they did not exist. Update
+// takes a task in the project wherever the task names, another project or none, see Move.
+type ProjectStore struct {
+	Store
+	Project string
+}
+
+// unwrap returns the store a ProjectStore limits, s itself for other stores.
+func unwrap(s Store) Store {
+	if ps, ok := s.(*ProjectStore); ok {
+		return ps.Store
+	}
+	return s
+}
+
+func (s *ProjectStore) Create(t *types.Task) error {
+	t.Project = s.Project
+	return s.Store.Create(t)
+}
+
+func (s *ProjectStore) Get(id int64) (*types.Task, error) {
+	t, err := s.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Project != s.Project {
+		return nil, os.ErrNotExist
+	}
+	return t, nil
+}
+
+func (s *ProjectStore) Update(t *types.Task) error {
+	return s.UpdateMany([]*types.Task{t})
+}
+
+func (s *ProjectStore) UpdateMany(ts []*types.Task) error {
+	for _, t := range ts {
+		if _, err := s.Get(t.ID); err != nil {
+			return err
+		}
+	}
+	return s.Store.UpdateMany(ts)
+}
+
+func (s *ProjectStore) Delete(id int64) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.Store.Delete(id)
+}
+
+func (s *ProjectStore) Restore(id int64) error {
+	if err := s.checkTrashed(id); err != nil {
+		return err
+	}
+	return s.Store.Restore(id)
+}
+
+func (s *ProjectStore) Purge(id int64) error {
+	if err := s.checkTrashed(id); err != nil {
+		return err
+	}
+	return s.Store.Purge(id)
+}
+
+// checkTrashed fails with os.ErrNotExist unless the task is in the trash and in the project.
+func (s *ProjectStore) checkTrashed(id int64) error {
+	trashed, err := s.Trash()
+	if err != nil {
+		return err
+	}
+	for _, t := range trashed {
+		if t.ID == id {
+			return nil
+		}
+	}
+	return os.ErrNotExist
+}
+
+func (s *ProjectStore) List() ([]*types.Task, error) {
+	return s.filter(s.Store.List())
+}
+
+func (s *ProjectStore) Trash() ([]*types.Task, error) {
+	return s.filter(s.Store.Trash())
+}
+
+func (s *ProjectStore) Query(f *types.Filter) ([]*types.Task, error) {
+	return s.filter(s.Store.Query(f))
+}
+
+// History leaves out the history of tasks in other projects. The history of a purged
+// task is returned whatever its project was.
+func (s *ProjectStore) History(id int64) ([]types.Event, error) {
+	t, err := s.Store.Get(id)
+	if errors.Is(err, os.ErrNotExist) {
+		trashed, err := s.Store.Trash()
+		if err != nil {
+			return nil, err
+		}
+		for _, tt := range trashed {
+			if tt.ID == id {
+				t = tt
+			}
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if t != nil && t.Project != s.Project {
+		return nil, os.ErrNotExist
+	}
+	return s.Store.History(id)
+}
+
+func (s *ProjectStore) filter(arr []*types.Task, err error) ([]*types.Task, error) {
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*types.Task, 0, len(arr))
+	for _, t := range arr {
+		if t.Project == s.Project {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,142 @@
+package task
+
+import (
+	"os"
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjects(t *testing.T) {
+	s := newTestJSONStore(t)
+
+	t.Run("registry", func(t *testing.T) {
+		require.NoError(t, s.AddProject(&types.Project{Name: "Billing", Description: "invoices"}))
+		require.NoError(t, s.AddProject(&types.Project{Name: "auth"}))
+		require.ErrorIs(t, s.AddProject(&types.Project{Name: "billing"}), ErrProjectExists)
+		assert.Error(t, s.AddProject(&types.Project{Name: "two words"}))
+
+		projects, err := s.Projects()
+		require.NoError(t, err)
+		require.Len(t, projects, 2)
+		assert.Equal(t, "auth", projects[0].Name)
+		assert.Equal(t, "billing", projects[1].Name, "names are folded to lower case")
+		_, err = s.Project("search")
+		require.ErrorIs(t, err, os.ErrNotExist)
+		assert.EqualError(t, err, `project "search" does not exist`)
+	})
+
+	billing := &ProjectStore{Store: s, Project: "billing"}
+	auth := &ProjectStore{Store: s, Project: "auth"}
+	require.NoError(t, s.Create(&types.Task{Description: "no project"}))
+	require.NoError(t, billing.Create(&types.Task{Description: "send invoices"}))
+	require.NoError(t, Add(billing, &types.Task{Description: "print them", ParentID: 2}))
+	require.NoError(t, auth.Create(&types.Task{Description: "rotate keys", Status: types.StatusDone}))
+
+	t.Run("a project store only sees its tasks", func(t *testing.T) {
+		all, err := billing.List()
+		require.NoError(t, err)
+		require.Len(t, all, 2)
+		assert.Equal(t, "billing", all[1].Project)
+
+		_, err = billing.Get(4)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		require.ErrorIs(t, billing.Delete(4), os.ErrNotExist)
+		_, err = billing.History(4)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		require.ErrorIs(t, Add(billing, &types.Task{Description: "sub", ParentID: 4}), ErrParent, "the parent is in another project")
+
+		arr, err := Search(billing, "keys", time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, arr)
+		arr, err = Search(auth, "keys", time.Now())
+		require.NoError(t, err)
+		require.Len(t, arr, 1)
+	})
+
+	t.Run("ids stay global", func(t *testing.T) {
+		path, err := SearchByID(3, s.IndexDir, s.TaskDir)
+		require.NoError(t, err)
+		assert.NotEmpty(t, path)
+		got, err := s.Get(3)
+		require.NoError(t, err)
+		assert.Equal(t, "billing", got.Project)
+	})
+
+	t.Run("progress by project", func(t *testing.T) {
+		all, err := s.List()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]types.Progress{"": {Total: 1}, "billing": {Total: 2}, "auth": {Done: 1, Total: 1}}, ProjectProgress(all))
+	})
+
+	t.Run("move takes the subtasks along", func(t *testing.T) {
+		_, err := Move(s, 3, "auth")
+		require.ErrorIs(t, err, ErrParent)
+
+		moved, err := Move(billing, 2, "auth")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2, 3}, moved)
+		left, err := billing.List()
+		require.NoError(t, err)
+		assert.Empty(t, left)
+		got, err := auth.Get(3)
+		require.NoError(t, err)
+		assert.Equal(t, "auth", got.Project)
+
+		events, err := s.History(2)
+		require.NoError(t, err)
+		assert.Equal(t, []types.Change{{Field: "project", Old: "billing", New: "auth"}}, events[len(events)-1].Changes)
+	})
+
+	t.Run("a move is undone as a whole", func(t *testing.T) {
+		steps, err := s.Undo(1)
+		require.NoError(t, err)
+		require.Len(t, steps, 1)
+		all, err := billing.List()
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+		events, err := s.History(3)
+		require.NoError(t, err)
+		assert.Equal(t, types.OpUndo, events[len(events)-1].Op)
+
+		_, err = s.Redo(1)
+		require.NoError(t, err)
+		all, err = billing.List()
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("a project store takes tasks out of the project", func(t *testing.T) {
+		require.NoError(t, s.Create(&types.Task{Description: "loose"}))
+		moved, err := Move(s, 5, "auth")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{5}, moved)
+		moved, err = Move(auth, 5, "")
+		require.NoError(t, err)
+		assert.Equal(t, []int64{5}, moved)
+		got, err := s.Get(5)
+		require.NoError(t, err)
+		assert.Empty(t, got.Project)
+	})
+
+	t.Run("remove only empty projects", func(t *testing.T) {
+		require.NoError(t, auth.Delete(4))
+		require.ErrorIs(t, RemoveProject(s, s, "auth"), ErrProjectInUse)
+		require.NoError(t, RemoveProject(s, s, "billing"))
+		require.ErrorIs(t, RemoveProject(s, s, "billing"), os.ErrNotExist)
+
+		moved, err := Move(s, 2, "")
+		require.NoError(t, err)
+		assert.Len(t, moved, 2)
+		require.NoError(t, s.Purge(4))
+		require.NoError(t, s.AddTemplate(&types.Template{Description: "rotate keys", Schedule: "weekly:mon", Project: "auth"}))
+		err = RemoveProject(s, s, "auth")
+		require.ErrorIs(t, err, ErrProjectInUse)
+		assert.Contains(t, err.Error(), "remove the 1 recurring task templates of project auth first")
+		require.NoError(t, s.RemoveTemplate(1))
+		require.NoError(t, RemoveProject(s, s, "auth"))
+	})
+}
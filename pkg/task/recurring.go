@@ -0,0 +1,171 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"taskTracker/pkg/recur"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// templateFile is kept in JSONStore.RecurPath. LastID only grows, so a removed
+// template's ID is not given to a new one.
+type templateFile struct {
+	LastID    int64             `json:"last_id"`
+	Templates []*types.Template `json:"templates"`
+}
+
+func (s *JSONStore) readTemplates() (*templateFile, error) {
+	f := &templateFile{Templates: make([]*types.Template, 0)}
+	data, err := os.ReadFile(s.RecurPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return f, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *JSONStore) writeTemplates(tx *utils.Tx, f *templateFile) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tx.Write(s.RecurPath, data)
+	return nil
+}
+
+// AddTemplate checks the schedule of tpl, assigns it the next template ID and saves it.
+func (s *JSONStore) AddTemplate(tpl *types.Template) error {
+	if _, err := recur.Parse(tpl.Schedule); err != nil {
+		return err
+	}
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	f, err := s.readTemplates()
+	if err != nil {
+		return err
+	}
+	f.LastID++
+	tpl.ID = f.LastID
+	if tpl.CreatedAt.IsZero() {
+		tpl.CreatedAt = time.Now().Local()
+	}
+	f.Templates = append(f.Templates, tpl)
+	tx := utils.Begin(s.JournalPath, "add template")
+	if err := s.writeTemplates(tx, f); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Templates returns the recurring task templates ordered by ID.
+func (s *JSONStore) Templates() ([]*types.Template, error) {
+	l, err := s.lockShared()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	f, err := s.readTemplates()
+	if err != nil {
+		return nil, err
+	}
+	return f.Templates, nil
+}
+
+// RemoveTemplate stops a template from creating tasks. The tasks it created stay.
+func (s *JSONStore) RemoveTemplate(id int64) error {
+	l, err := s.lockExclusive()
+	if err != nil {
+		return err
+	}
+	defer l.Unlock()
+
+	f, err := s.readTemplates()
+	if err != nil {
+		return err
+	}
+	for i, tpl := range f.Templates {
+		if tpl.ID == id {
+			f.Templates = append(f.Templates[:i], f.Templates[i+1:]...)
+			tx := utils.Begin(s.JournalPath, "remove template")
+			if err := s.writeTemplates(tx, f); err != nil {
+				return err
+			}
+			return tx.Commit()
+		}
+	}
+	return &NotFoundError{What: fmt.Sprintf("recurring task template %d", id)}
+}
+
+// Materialize creates a task for the latest occurrence at or before now of every
+// template that has no task for it yet. Occurrences missed in between are skipped, a
+// daily standup not run for a week gives one task and not seven, and so are the ones
+// before the day the template was added. A task is created at its occurrence, so it is
+// listed on the day it was due. The task is created through CreateTask in the
+// same transaction that moves Template.Last forward, so a crash can not leave a task
+// without its occurrence being recorded or the other way around. It returns the new tasks.
+func (s *JSONStore) Materialize(now time.Time) ([]*types.Task, error) {
+	l, err := s.lockExclusive()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	f, err := s.readTemplates()
+	if err != nil {
+		return nil, err
+	}
+	created := make([]*types.Task, 0)
+	for _, tpl := range f.Templates {
+		sched, err := recur.Parse(tpl.Schedule)
+		if err != nil {
+			return created, err
+		}
+		occ, ok := sched.Prev(now)
+		y, m, d := tpl.CreatedAt.Date()
+		if !ok || occ.Before(time.Date(y, m, d, 0, 0, 0, 0, tpl.CreatedAt.Location())) || !occ.After(tpl.Last) {
+			continue
+		}
+
+		lastID, err := utils.ReadLastID(s.LastIDPath)
+		if err != nil {
+			return created, err
+		}
+		t := &types.Task{
+			Description: tpl.Description,
+			Priority:    tpl.Priority,
+			Tags:        append([]string(nil), tpl.Tags...),
+			Project:     tpl.Project,
+			CreatedAt:   occ,
+			UpdateAt:    now,
+		}
+		prepareNew(t, s.Actor)
+		t.ID = lastID + 1
+		tx := utils.Begin(s.JournalPath, "create")
+		if err := CreateTask(tx, t, s.TaskDir, s.IndexDir, s.LastIDPath); err != nil {
+			return created, err
+		}
+		tpl.Last = occ
+		if err := s.writeTemplates(tx, f); err != nil {
+			return created, err
+		}
+		if err := s.commit(tx, monthFile(s.TaskDir, t.CreatedAt), types.NewEvent(types.OpCreate, s.Actor, nil, t)); err != nil {
+			return created, err
+		}
+		created = append(created, t)
+	}
+	return created, nil
+}
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONStoreMaterialize(t *testing.T) {
+	s := newTestJSONStore(t)
+	monday := time.Date(2026, time.October, 12, 8, 0, 0, 0, time.Local)
+	require.NoError(t, s.AddTemplate(&types.Template{Description: "standup", Schedule: "weekdays", Tags: []string{"team"}, CreatedAt: monday}))
+	require.NoError(t, s.AddTemplate(&types.Template{Description: "report", Schedule: "weekly:fri", CreatedAt: monday}))
+	require.Error(t, s.AddTemplate(&types.Template{Description: "bad", Schedule: "hourly"}))
+
+	created, err := s.Materialize(monday)
+	require.NoError(t, err)
+	require.Len(t, created, 1, "the report of the friday before the template was added is not created")
+	assert.Equal(t, "standup", created[0].Description)
+	assert.Equal(t, []string{"team"}, created[0].Tags)
+	assert.True(t, created[0].CreatedAt.Equal(time.Date(2026, time.October, 12, 0, 0, 0, 0, time.Local)), "created at the occurrence")
+
+	created, err = s.Materialize(monday.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, created, "one task per occurrence")
+
+	// a week later: one standup for friday, the missed days are skipped, and the report
+	created, err = s.Materialize(monday.AddDate(0, 0, 6))
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	templates, err := s.Templates()
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	friday := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.Local)
+	assert.True(t, templates[0].Last.Equal(friday))
+	assert.True(t, templates[1].Last.Equal(friday))
+
+	all, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	t.Run("undo takes the occurrence back", func(t *testing.T) {
+		_, err := s.Undo(1)
+		require.NoError(t, err)
+		created, err := s.Materialize(monday.AddDate(0, 0, 6))
+		require.NoError(t, err)
+		require.Len(t, created, 1)
+		assert.Equal(t, "report", created[0].Description)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.NoError(t, s.RemoveTemplate(1))
+		require.Error(t, s.RemoveTemplate(1))
+		require.NoError(t, s.AddTemplate(&types.Template{Description: "retro", Schedule: "monthly:1"}))
+		templates, err := s.Templates()
+		require.NoError(t, err)
+		require.Len(t, templates, 2)
+		assert.Equal(t, int64(3), templates[1].ID, "ids are not reused")
+	})
+}
@@ -0,0 +1,229 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"taskTracker/pkg/search"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// textIndexName is the file of the index directory holding the full-text index of the
+// task descriptions, see package search.
+const textIndexName = "search.json"
+
+// textLogName is the file next to the full-text index that changes to it are appended
+// to, one textEntry per line, so indexing a task does not rewrite the whole index.
+// Reading the index replays the log. Once the log outgrows the index it is folded into
+// it, fsck does that too.
+const textLogName = "search.log"
+
+// textLogMin is the size up to which the log is kept even when the index is smaller.
+var textLogMin int64 = 64 << 10
+
+// textEntry is one line of the text log: the description of a task, or its removal.
+type textEntry struct {
+	ID        int64     `json:"id"`
+	Text      string    `json:"text,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	Remove    bool      `json:"remove,omitempty"`
+}
+
+func textIndexFile(iStorage string) string {
+	return filepath.Join(iStorage, textIndexName)
+}
+
+func textLogFile(iStorage string) string {
+	return filepath.Join(iStorage, textLogName)
+}
+
+// readTextIndex reads the full-text index and replays its log, preferring what tx has
+// already staged for them; tx may be nil. Without an index it is built from the month
+// files and saved when the log is folded into it.
+func readTextIndex(tx *utils.Tx, iStorage, tStorage string) (*search.Index, error) {
+	fPath := textIndexFile(iStorage)
+	var data []byte
+	if f, ok := pending(tx, fPath); ok {
+		if f.Remove {
+			return search.New(), nil
+		}
+		data = f.Data
+	} else {
+		var err error
+		if data, err = os.ReadFile(fPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	ix := search.New()
+	if data == nil {
+		var err error
+		if ix, err = scanText(tStorage); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, ix); err != nil {
+		return nil, fmt.Errorf("%s: %w", fPath, err)
+	}
+
+	logData, err := readTextLog(tx, iStorage)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range bytes.Split(logData, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var e textEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", textLogFile(iStorage), i+1, err)
+		}
+		if e.Remove {
+			ix.Remove(e.ID)
+		} else {
+			ix.Add(e.ID, e.Text, e.UpdatedAt)
+		}
+	}
+	return ix, nil
+}
+
+// readTextLog returns the text log with what tx appends to it.
+func readTextLog(tx *utils.Tx, iStorage string) ([]byte, error) {
+	fPath := textLogFile(iStorage)
+	f, ok := pending(tx, fPath)
+	if ok && f.Remove {
+		return nil, nil
+	}
+	data, err := os.ReadFile(fPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if ok {
+		data = f.Content(data)
+	}
+	return data, nil
+}
+
+func pending(tx *utils.Tx, fPath string) (utils.FileWrite, bool) {
+	if tx == nil {
+		return utils.FileWrite{}, false
+	}
+	return tx.Pending(fPath)
+}
+
+// writeTextIndex stages the full-text index and drops its log, which ix holds.
+func writeTextIndex(tx *utils.Tx, iStorage string, ix *search.Index) error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	tx.Write(textIndexFile(iStorage), data)
+	tx.Remove(textLogFile(iStorage))
+	return nil
+}
+
+// indexText stages appending t to the log of the full-text index, or the removal of
+// the task when t is nil. A log grown bigger than the index is folded into it.
+func indexText(tx *utils.Tx, iStorage, tStorage string, id int64, t *types.Task) error {
+	e := textEntry{ID: id, Remove: t == nil}
+	if t != nil {
+		e.Text, e.UpdatedAt = t.Description, t.UpdateAt
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := tx.Append(textLogFile(iStorage), append(line, '\n')); err != nil {
+		return err
+	}
+
+	logData, err := readTextLog(tx, iStorage)
+	if err != nil {
+		return err
+	}
+	var indexSize int64
+	if info, err := os.Stat(textIndexFile(iStorage)); err == nil {
+		indexSize = info.Size()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if int64(len(logData)) <= max(indexSize, textLogMin) {
+		return nil
+	}
+	ix, err := readTextIndex(tx, iStorage, tStorage)
+	if err != nil {
+		return err
+	}
+	return writeTextIndex(tx, iStorage, ix)
+}
+
+// scanText builds the full-text index from the month files. Month files that do not
+// decode are skipped, Fsck reports them.
+func scanText(tStorage string) (*search.Index, error) {
+	ix := search.New()
+	err := filepath.WalkDir(tStorage, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, ok := monthOf(path, d); !ok {
+			return nil
+		}
+		tMap := make(map[int64]*types.Task)
+		if err := utils.DecodeTasks(path, tMap); err != nil {
+			return nil
+		}
+		for id, t := range tMap {
+			ix.Add(id, t.Description, t.UpdateAt)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Search returns the tasks whose description matches every word of q, fully or as a
+// prefix, the most relevant and recently changed first; see package search. The JSON
+// storage keeps the index in its index directory, other stores are indexed on the fly.
+// Tasks in the trash, and those of other projects for a ProjectStore, are left out.
+func Search(s Store, q string, now time.Time) ([]*types.Task, error) {
+	inner := unwrap(s)
+	var ix *search.Index
+	if js, ok := inner.(*JSONStore); ok {
+		l, err := js.lockShared()
+		if err != nil {
+			return nil, err
+		}
+		ix, err = readTextIndex(nil, js.IndexDir, js.TaskDir)
+		l.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		all, err := s.List()
+		if err != nil {
+			return nil, err
+		}
+		ix = search.New()
+		for _, t := range all {
+			ix.Add(t.ID, t.Description, t.UpdateAt)
+		}
+	}
+
+	res := make([]*types.Task, 0)
+	for _, r := range ix.Search(q, now) {
+		t, err := s.Get(r.ID)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, nil
+}
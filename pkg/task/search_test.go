@@ -0,0 +1,100 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	descs := func(arr []*types.Task) []string {
+		res := make([]string, 0, len(arr))
+		for _, t := range arr {
+			res = append(res, t.Description)
+		}
+		return res
+	}
+	for name, s := range map[string]Store{"json": newTestJSONStore(t), "mem": NewMemStore()} {
+		t.Run(name, func(t *testing.T) {
+			for _, desc := range []string{"Postgres migration plan", "rotate certificates", "migrate billing to Postgres"} {
+				require.NoError(t, s.Create(&types.Task{Description: desc}))
+			}
+			arr, err := Search(s, "postgres migr", time.Now())
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"Postgres migration plan", "migrate billing to Postgres"}, descs(arr))
+
+			got, err := s.Get(2)
+			require.NoError(t, err)
+			got.Description = "rotate postgres certificates"
+			require.NoError(t, s.Update(got))
+			arr, err = Search(s, "certificates", time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, []string{"rotate postgres certificates"}, descs(arr))
+
+			require.NoError(t, s.Delete(1))
+			arr, err = Search(s, "plan", time.Now())
+			require.NoError(t, err)
+			assert.Empty(t, arr, "tasks in the trash are left out")
+			require.NoError(t, s.Purge(1))
+		})
+	}
+
+	t.Run("the json index follows undo and can be rebuilt", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		require.NoError(t, s.Create(&types.Task{Description: "write the release notes"}))
+		_, err := s.Undo(1)
+		require.NoError(t, err)
+		arr, err := Search(s, "release", time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, arr)
+
+		require.NoError(t, s.Create(&types.Task{Description: "write the release notes"}))
+		require.NoError(t, os.Remove(filepath.Join(s.IndexDir, textLogName)))
+		arr, err = Search(s, "release", time.Now())
+		require.NoError(t, err)
+		require.Len(t, arr, 1, "without an index the month files are searched")
+
+		_, err = s.Fsck(false, true)
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(s.IndexDir, textIndexName))
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"release"`)
+		_, err = os.Stat(filepath.Join(s.IndexDir, textLogName))
+		assert.ErrorIs(t, err, os.ErrNotExist, "folded into the index")
+	})
+
+	t.Run("writes append to the log", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		logPath := filepath.Join(s.IndexDir, textLogName)
+		size := func() int64 {
+			info, err := os.Stat(logPath)
+			require.NoError(t, err)
+			return info.Size()
+		}
+		require.NoError(t, s.Create(&types.Task{Description: "write the release notes"}))
+		before := size()
+		_, err := Mark(s, 1, types.StatusDone, false)
+		require.NoError(t, err)
+		assert.Equal(t, before, size(), "the description did not change")
+
+		defer func(n int64) { textLogMin = n }(textLogMin)
+		textLogMin = 0
+		require.NoError(t, s.Create(&types.Task{Description: "publish the release"}))
+		_, err = os.Stat(logPath)
+		assert.ErrorIs(t, err, os.ErrNotExist, "folded into the index")
+		require.NoError(t, s.Create(&types.Task{Description: "announce it"}))
+		assert.Positive(t, size())
+
+		arr, err := Search(s, "release", time.Now())
+		require.NoError(t, err)
+		assert.Len(t, arr, 2)
+		arr, err = Search(s, "announce", time.Now())
+		require.NoError(t, err)
+		assert.Len(t, arr, 1)
+	})
+}
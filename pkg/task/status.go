@@ -0,0 +1,58 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// ErrTransition is returned when a change would move a task to a status it can not reach from its current one.
+var ErrTransition = errors.New("illegal status transition")
+
+// checkTransition is run by every Store.Update before old is replaced by updated.
+func checkTransition(old, updated *types.Task) error {
+	if !types.CanTransition(old.Status, updated.Status) {
+		return fmt.Errorf("%w: task %d can not go from %s to %s", ErrTransition, old.ID, old.Status, updated.Status)
+	}
+	return nil
+}
+
+// Mark moves the task with the given id to status to and saves it with Save.
+func Mark(s Store, id int64, to types.Status, force bool) (*types.Task, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status == to {
+		return t, nil
+	}
+	if !types.CanTransition(t.Status, to) {
+		return nil, fmt.Errorf("%w: task %d can not go from %s to %s", ErrTransition, t.ID, t.Status, to)
+	}
+	t.Status = to
+	t.UpdateAt = time.Now().Local()
+	if err := Save(s, t, force); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Assign makes user the assignee of the task with the given id, "" leaves it unassigned.
+func Assign(s Store, id int64, user string) (*types.Task, error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	user = strings.TrimSpace(user)
+	if t.Assignee == user {
+		return t, nil
+	}
+	t.Assignee = user
+	t.UpdateAt = time.Now().Local()
+	if err := s.Update(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,156 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// Store is a task storage backend. Lookups of a missing task return os.ErrNotExist.
+type Store interface {
+	// Create assigns the next free ID to t, fills empty timestamps and CreatedBy and
+	// saves it.
+	Create(t *types.Task) error
+	Get(id int64) (*types.Task, error)
+	// Update replaces the stored task with the same ID as t. A status change that
+	// types.CanTransition does not allow fails with ErrTransition, a t read before the
+	// stored task was last updated with ErrStale.
+	Update(t *types.Task) error
+	// UpdateMany is Update for several tasks at once: either all of them are replaced or,
+	// when one fails, none.
+	UpdateMany(ts []*types.Task) error
+	// Delete moves a task to the trash. Get, Update and List leave trashed tasks out,
+	// Query only returns them when the filter asks for them.
+	Delete(id int64) error
+	// Restore takes a task out of the trash, Purge removes it for good. Both fail with
+	// os.ErrNotExist for a task that is not in the trash.
+	Restore(id int64) error
+	Purge(id int64) error
+	// List returns every stored task ordered by ID.
+	List() ([]*types.Task, error)
+	// Trash returns the tasks in the trash, the longest deleted first.
+	Trash() ([]*types.Task, error)
+	// Query returns the tasks passing f ordered by CreatedAt.
+	Query(f *types.Filter) ([]*types.Task, error)
+	// History returns the audit log of a task, oldest event first. It outlives the task:
+	// a deleted task still has its history.
+	History(id int64) ([]types.Event, error)
+}
+
+// NotFoundError names what a lookup of something other than a task, like a project
+// or a template, did not find. It matches os.ErrNotExist.
+type NotFoundError struct {
+	What string // e.g. project "billing"
+}
+
+func (e *NotFoundError) Error() string {
+	return e.What + " does not exist"
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return os.ErrNotExist
+}
+
+// ErrStale is returned when a task is written back after someone else updated it since
+// it was read, which would throw their change away. Reading it again and redoing the
+// change is safe.
+var ErrStale = errors.New("task was changed since it was read")
+
+// IsConflict reports whether err is a change refused by a rule of the task model, like
+// an illegal status transition, rather than a failure of the storage.
+func IsConflict(err error) bool {
+	for _, target := range []error{ErrTransition, ErrUndoConflict, ErrUndoScope, ErrParent, ErrOpenSubtasks, ErrHasSubtasks, ErrDependency, ErrCycle, ErrProjectExists, ErrProjectInUse, ErrNoTimer, ErrTimerRunning, ErrImportConflict, ErrStale} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareNew fills what Create leaves to the store: timestamps, the initial status and
+// the user creating the task, actor. A task created done was done when it was created.
+func prepareNew(t *types.Task, actor string) {
+	now := time.Now().Local()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.UpdateAt.IsZero() {
+		t.UpdateAt = now
+	}
+	if t.Status == "" {
+		t.Status = types.StatusTodo
+	}
+	if t.CreatedBy == "" {
+		t.CreatedBy = actor
+	}
+	switch {
+	case !t.Done():
+		t.DoneAt = time.Time{}
+	case t.DoneAt.IsZero():
+		t.DoneAt = t.UpdateAt
+	}
+	t.DeletedAt = time.Time{}
+	t.Revision = 0
+}
+
+// prepareUpdate is run by every Store.Update before old is replaced by updated. Tasks in
+// the trash can not be changed and Update does not move tasks in or out of it. Who
+// created a task does not change either, DoneAt is stamped by stampDone. updated has to
+// be based on the stored revision, which it then moves on.
+func prepareUpdate(old, updated *types.Task) error {
+	if old.Deleted() {
+		return os.ErrNotExist
+	}
+	if updated.Revision != old.Revision {
+		return fmt.Errorf("%w: task %d, try again", ErrStale, old.ID)
+	}
+	updated.DeletedAt = old.DeletedAt
+	updated.CreatedBy = old.CreatedBy
+	if err := checkTransition(old, updated); err != nil {
+		return err
+	}
+	stampDone(old, updated)
+	updated.Revision++
+	return nil
+}
+
+// keepRevisions saves the revisions of ts, the returned func puts them back. An
+// UpdateMany that fails calls it, so the tasks prepared before the failing one can be
+// written again and are not refused as stale.
+func keepRevisions(ts []*types.Task) func() {
+	revs := make([]int64, len(ts))
+	for i, t := range ts {
+		revs[i] = t.Revision
+	}
+	return func() {
+		for i, t := range ts {
+			t.Revision = revs[i]
+		}
+	}
+}
+
+// stampDone keeps DoneAt at the time the task was marked done: it is set when the task
+// becomes done, kept while it stays done and cleared when the task is reopened.
+func stampDone(old, updated *types.Task) {
+	switch {
+	case !updated.Done():
+		updated.DoneAt = time.Time{}
+	case old.Done():
+		updated.DoneAt = old.CompletedAt()
+	case updated.DoneAt.IsZero():
+		updated.DoneAt = time.Now().Local()
+	}
+}
+
+// sortByDeleted orders trashed tasks by deletion time, ID breaking ties.
+func sortByDeleted(arr []*types.Task) {
+	sort.Slice(arr, func(i, j int) bool {
+		if !arr[i].DeletedAt.Equal(arr[j].DeletedAt) {
+			return arr[i].DeletedAt.Before(arr[j].DeletedAt)
+		}
+		return arr[i].ID < arr[j].ID
+	})
+}
@@ -0,0 +1,557 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJSONStore(t *testing.T) *JSONStore {
+	dir := t.TempDir()
+	s := NewJSONStore(filepath.Join(dir, "tasks"), filepath.Join(dir, "index"), filepath.Join(dir, "lastID.json"))
+	require.NoError(t, os.MkdirAll(s.TaskDir, 0755))
+	require.NoError(t, os.MkdirAll(s.IndexDir, 0755))
+	return s
+}
+
+// testStore runs the behaviour every Store implementation has to share.
+func testStore(t *testing.T, s Store) {
+	t.Run("create assigns sequential ids", func(t *testing.T) {
+		first := &types.Task{Description: "first"}
+		second := &types.Task{Description: "second", Status: types.StatusDone}
+		require.NoError(t, s.Create(first))
+		require.NoError(t, s.Create(second))
+		assert.Equal(t, int64(1), first.ID)
+		assert.Equal(t, int64(2), second.ID)
+		assert.False(t, first.CreatedAt.IsZero())
+		assert.Equal(t, types.StatusTodo, first.Status)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		res, err := s.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "second", res.Description)
+		assert.True(t, res.Done())
+	})
+
+	t.Run("get missing -> os.ErrNotExist", func(t *testing.T) {
+		_, err := s.Get(100)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		res, err := s.Get(1)
+		require.NoError(t, err)
+		res.Description = "changed"
+		res.Status = types.StatusDone
+		require.NoError(t, s.Update(res))
+
+		res, err = s.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "changed", res.Description)
+		assert.True(t, res.Done())
+	})
+
+	t.Run("stale write -> ErrStale", func(t *testing.T) {
+		first, err := s.Get(1)
+		require.NoError(t, err)
+		second, err := s.Get(1)
+		require.NoError(t, err)
+		first.Assignee = "bob"
+		require.NoError(t, s.Update(first))
+		second.Description = "changed again"
+		require.ErrorIs(t, s.Update(second), ErrStale, "would drop the assignee")
+		assert.True(t, IsConflict(ErrStale))
+
+		first.Assignee = ""
+		require.NoError(t, s.Update(first), "the writer's copy is current")
+		res, err := s.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "changed", res.Description)
+	})
+
+	t.Run("illegal status transition", func(t *testing.T) {
+		_, err := Mark(s, 1, types.StatusTodo, false)
+		require.NoError(t, err)
+		res, err := Mark(s, 1, types.StatusCancelled, false)
+		require.NoError(t, err)
+
+		res.Status = types.StatusDone
+		require.ErrorIs(t, s.Update(res), ErrTransition)
+		_, err = Mark(s, 1, types.StatusDone, false)
+		require.ErrorIs(t, err, ErrTransition)
+
+		res, err = Mark(s, 1, types.StatusTodo, false)
+		require.NoError(t, err)
+		assert.Equal(t, types.StatusTodo, res.Status)
+		res, err = Mark(s, 1, types.StatusDone, false)
+		require.NoError(t, err)
+		assert.True(t, res.Done())
+	})
+
+	t.Run("creator and assignee", func(t *testing.T) {
+		got, err := s.Get(1)
+		require.NoError(t, err)
+		assert.NotEmpty(t, got.CreatedBy, "the store's actor created the task")
+		creator := got.CreatedBy
+		got.CreatedBy = "mallory"
+		require.NoError(t, s.Update(got))
+
+		res, err := Assign(s, 1, " bob ")
+		require.NoError(t, err)
+		assert.Equal(t, "bob", res.Assignee)
+		got, err = s.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", got.Assignee)
+		assert.Equal(t, creator, got.CreatedBy, "who created a task does not change")
+		events, err := s.History(1)
+		require.NoError(t, err)
+		assert.Equal(t, []types.Change{{Field: "assignee", New: "bob"}}, events[len(events)-1].Changes)
+
+		_, err = Assign(s, 1, "")
+		require.NoError(t, err)
+		_, err = Assign(s, 99, "bob")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("done at", func(t *testing.T) {
+		created, err := s.Get(2)
+		require.NoError(t, err)
+		assert.True(t, created.DoneAt.Equal(created.UpdateAt), "a task created done")
+
+		done, err := s.Get(1)
+		require.NoError(t, err)
+		require.False(t, done.DoneAt.IsZero())
+		done.Estimate = 3
+		doneAt := done.DoneAt
+		done.DoneAt = doneAt.Add(time.Hour)
+		require.NoError(t, s.Update(done))
+		got, err := s.Get(1)
+		require.NoError(t, err)
+		assert.True(t, got.DoneAt.Equal(doneAt), "kept while the task stays done")
+		assert.Equal(t, 3.0, got.Estimate)
+
+		got, err = Mark(s, 1, types.StatusTodo, false)
+		require.NoError(t, err)
+		assert.True(t, got.DoneAt.IsZero(), "cleared on reopen")
+		got, err = Mark(s, 1, types.StatusDone, false)
+		require.NoError(t, err)
+		assert.False(t, got.DoneAt.IsZero())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		arr, err := s.List()
+		require.NoError(t, err)
+		require.Len(t, arr, 2)
+		assert.Equal(t, int64(1), arr[0].ID)
+		assert.Equal(t, int64(2), arr[1].ID)
+	})
+
+	t.Run("query by date", func(t *testing.T) {
+		arr, err := s.Query(types.NewFilter())
+		require.NoError(t, err)
+		assert.Len(t, arr, 2)
+
+		f := types.NewFilter()
+		f.Day = time.Now().Local().AddDate(0, 0, 1).Day()
+		arr, err = s.Query(f)
+		require.NoError(t, err)
+		assert.Len(t, arr, 0)
+	})
+
+	t.Run("delete moves to the trash", func(t *testing.T) {
+		require.NoError(t, s.Delete(1))
+		_, err := s.Get(1)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		require.ErrorIs(t, s.Delete(1), os.ErrNotExist)
+
+		arr, err := s.List()
+		require.NoError(t, err)
+		assert.Len(t, arr, 1)
+		arr, err = s.Query(types.NewFilter())
+		require.NoError(t, err)
+		assert.Len(t, arr, 1)
+
+		trash, err := s.Trash()
+		require.NoError(t, err)
+		require.Len(t, trash, 1)
+		assert.Equal(t, "changed", trash[0].Description)
+		assert.True(t, trash[0].Deleted())
+
+		f := types.NewFilter()
+		f.Trashed = true
+		arr, err = s.Query(f)
+		require.NoError(t, err)
+		assert.Len(t, arr, 2)
+	})
+
+	t.Run("trashed tasks can not be updated", func(t *testing.T) {
+		require.ErrorIs(t, s.Update(&types.Task{ID: 1, Description: "x", Status: types.StatusDone}), os.ErrNotExist)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		require.ErrorIs(t, s.Restore(2), os.ErrNotExist)
+		require.NoError(t, s.Restore(1))
+		res, err := s.Get(1)
+		require.NoError(t, err)
+		assert.False(t, res.Deleted())
+		assert.Equal(t, int64(1), res.ID)
+		require.NoError(t, s.Delete(1))
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		require.ErrorIs(t, s.Purge(2), os.ErrNotExist)
+		require.NoError(t, s.Purge(1))
+		require.ErrorIs(t, s.Restore(1), os.ErrNotExist)
+		trash, err := s.Trash()
+		require.NoError(t, err)
+		assert.Empty(t, trash)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		events, err := s.History(1)
+		require.NoError(t, err)
+		require.NotEmpty(t, events)
+		first, last := events[0], events[len(events)-1]
+		assert.Equal(t, types.OpCreate, first.Op)
+		assert.Contains(t, first.Changes, types.Change{Field: "description", New: "first"})
+		assert.NotEmpty(t, first.Actor)
+		assert.Equal(t, types.OpPurge, last.Op)
+		assert.Contains(t, last.Changes, types.Change{Field: "description", Old: "changed"})
+		ops := make([]string, 0, len(events))
+		for _, ev := range events {
+			ops = append(ops, ev.Op)
+		}
+		assert.Contains(t, ops, types.OpRestore)
+		assert.Contains(t, events[1].Changes, types.Change{Field: "description", Old: "first", New: "changed"})
+		for _, ev := range events {
+			assert.Equal(t, int64(1), ev.TaskID)
+		}
+
+		events, err = s.History(2)
+		require.NoError(t, err)
+		assert.Len(t, events, 1)
+
+		_, err = s.History(100)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("update many is all or nothing", func(t *testing.T) {
+		require.NoError(t, s.Create(&types.Task{Description: "third"}))
+		second, err := s.Get(2)
+		require.NoError(t, err)
+		third, err := s.Get(3)
+		require.NoError(t, err)
+		second.Tags = []string{"batch"}
+		third.Tags = []string{"batch"}
+		require.ErrorIs(t, s.UpdateMany([]*types.Task{second, {ID: 99}}), os.ErrNotExist)
+		res, err := s.Get(2)
+		require.NoError(t, err)
+		assert.Empty(t, res.Tags, "the second task is not updated alone")
+
+		require.NoError(t, s.UpdateMany([]*types.Task{second, third}))
+		for _, id := range []int64{2, 3} {
+			res, err := s.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"batch"}, res.Tags)
+		}
+	})
+}
+
+func TestJSONStore(t *testing.T) {
+	testStore(t, newTestJSONStore(t))
+}
+
+func TestMemStore(t *testing.T) {
+	testStore(t, NewMemStore())
+}
+
+func TestJSONStoreRecover(t *testing.T) {
+	// crash after the journal was committed but before any file was replaced
+	crash := func(t *testing.T, s *JSONStore) {
+		tx := utils.Begin(s.JournalPath, "create")
+		second := &types.Task{ID: 2, Description: "second", CreatedAt: time.Now().Local()}
+		require.NoError(t, CreateTask(tx, second, s.TaskDir, s.IndexDir, s.LastIDPath))
+		data, err := json.Marshal(tx)
+		require.NoError(t, err)
+		require.NoError(t, utils.WriteFileAtomic(s.JournalPath, data))
+	}
+	check := func(t *testing.T, s *JSONStore) {
+		res, err := s.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "second", res.Description)
+		lastID, err := utils.ReadLastID(s.LastIDPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), lastID)
+	}
+
+	t.Run("on startup", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		require.NoError(t, s.Create(&types.Task{Description: "first"}))
+		crash(t, s)
+		ok, err := s.Recover()
+		require.NoError(t, err)
+		assert.True(t, ok)
+		check(t, s)
+	})
+
+	t.Run("by the next reader", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		require.NoError(t, s.Create(&types.Task{Description: "first"}))
+		crash(t, s)
+		check(t, s)
+		ok, err := s.Recover()
+		require.NoError(t, err)
+		assert.False(t, ok, "already finished")
+	})
+
+	t.Run("by the next writer", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		require.NoError(t, s.Create(&types.Task{Description: "first"}))
+		crash(t, s)
+		require.NoError(t, s.Create(&types.Task{Description: "third"}))
+		res, err := s.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "second", res.Description)
+		res, err = s.Get(3)
+		require.NoError(t, err)
+		assert.Equal(t, "third", res.Description)
+	})
+}
+
+func TestJSONStoreUndo(t *testing.T) {
+	s := newTestJSONStore(t)
+	require.NoError(t, s.Create(&types.Task{Description: "keep"}))
+	require.NoError(t, s.Create(&types.Task{Description: "wrong"}))
+	lastIDBefore, err := os.ReadFile(s.LastIDPath)
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(1))
+
+	t.Run("undo restores the deleted task", func(t *testing.T) {
+		steps, err := s.Undo(1)
+		require.NoError(t, err)
+		require.Len(t, steps, 1)
+		assert.Equal(t, "delete", steps[0].Op)
+		res, err := s.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "keep", res.Description)
+	})
+
+	t.Run("undo of a create gives back its id", func(t *testing.T) {
+		steps, err := s.Undo(5)
+		require.NoError(t, err)
+		assert.Len(t, steps, 2)
+		arr, err := s.List()
+		require.NoError(t, err)
+		assert.Empty(t, arr)
+		lastID, err := utils.ReadLastID(s.LastIDPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), lastID)
+	})
+
+	t.Run("redo applies the changes again", func(t *testing.T) {
+		steps, err := s.Redo(2)
+		require.NoError(t, err)
+		assert.Len(t, steps, 2)
+		data, err := os.ReadFile(s.LastIDPath)
+		require.NoError(t, err)
+		assert.Equal(t, lastIDBefore, data)
+		arr, err := s.List()
+		require.NoError(t, err)
+		assert.Len(t, arr, 2)
+	})
+
+	t.Run("a new change drops the redo steps", func(t *testing.T) {
+		require.NoError(t, s.Create(&types.Task{Description: "third"}))
+		steps, err := s.Redo(1)
+		require.NoError(t, err)
+		assert.Empty(t, steps)
+	})
+
+	t.Run("undo is recorded in the history", func(t *testing.T) {
+		events, err := s.History(1)
+		require.NoError(t, err)
+		ops := make([]string, 0, len(events))
+		for _, ev := range events {
+			ops = append(ops, ev.Op)
+		}
+		assert.Equal(t, []string{"create", "delete", "undo", "undo", "redo"}, ops)
+	})
+
+	t.Run("files changed behind the store's back -> conflict", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(s.LastIDPath, []byte(`{"lastID":42}`), 0644))
+		_, err := s.Undo(1)
+		require.ErrorIs(t, err, ErrUndoConflict)
+	})
+
+	t.Run("a change to another task is no conflict", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		require.NoError(t, s.Create(&types.Task{Description: "first"}))
+		require.NoError(t, s.Create(&types.Task{Description: "second"}))
+		require.NoError(t, s.Delete(1))
+		require.NoError(t, s.Purge(1))
+
+		// task 2 changed without the undo history knowing
+		s.UndoLimit = 0
+		second, err := s.Get(2)
+		require.NoError(t, err)
+		second.Description = "second, edited"
+		require.NoError(t, s.Update(second))
+		s.UndoLimit = DefaultUndoLimit
+
+		_, err = s.Undo(2)
+		require.NoError(t, err)
+		res, err := s.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, "first", res.Description)
+		found, err := Search(s, "first", time.Now())
+		require.NoError(t, err)
+		assert.Len(t, found, 1, "back in the full-text index")
+		res, err = s.Get(2)
+		require.NoError(t, err)
+		assert.Equal(t, "second, edited", res.Description)
+
+		require.NoError(t, s.Create(&types.Task{Description: "third"}))
+		_, err = s.Undo(1)
+		require.NoError(t, err)
+		_, err = s.Get(3)
+		require.ErrorIs(t, err, os.ErrNotExist, "the ID index forgot it")
+		found, err = Search(s, "third", time.Now())
+		require.NoError(t, err)
+		assert.Empty(t, found)
+
+		_, err = s.Undo(1)
+		require.ErrorIs(t, err, ErrUndoConflict, "task 2 itself changed")
+	})
+
+	t.Run("steps keep only what changed", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		s.UndoLimit = 5
+		size := func() int64 {
+			info, err := os.Stat(s.UndoPath)
+			require.NoError(t, err)
+			return info.Size()
+		}
+		for i := 0; i < 10; i++ {
+			require.NoError(t, s.Create(&types.Task{Description: "a task with a few words in it"}))
+		}
+		small := size()
+		for i := 0; i < 100; i++ {
+			require.NoError(t, s.Create(&types.Task{Description: "a task with a few words in it"}))
+		}
+		assert.Less(t, size(), small*2, "does not grow with the store")
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		s := newTestJSONStore(t)
+		s.UndoLimit = 2
+		for i := 0; i < 4; i++ {
+			require.NoError(t, s.Create(&types.Task{Description: "task"}))
+		}
+		steps, err := s.Undo(10)
+		require.NoError(t, err)
+		assert.Len(t, steps, 2)
+	})
+
+	t.Run("only own changes of the project", func(t *testing.T) {
+		alice := newTestJSONStore(t)
+		alice.Actor = "alice"
+		bob := *alice
+		bob.Actor = "bob"
+		require.NoError(t, alice.Create(&types.Task{Description: "alice's", Project: "billing"}))
+		require.NoError(t, bob.Create(&types.Task{Description: "bob's"}))
+
+		_, err := alice.Undo(1)
+		require.ErrorIs(t, err, ErrUndoScope)
+		assert.Contains(t, err.Error(), "it was made by bob")
+		assert.True(t, IsConflict(err))
+		_, err = bob.Get(2)
+		require.NoError(t, err, "bob's task is kept")
+
+		bob.UndoProject = "billing"
+		_, err = bob.Undo(1)
+		require.ErrorIs(t, err, ErrUndoScope)
+		assert.Contains(t, err.Error(), "task 2 is not in project billing")
+		bob.UndoProject = ""
+		steps, err := bob.Undo(1)
+		require.NoError(t, err)
+		require.Len(t, steps, 1)
+
+		alice.UndoProject = "billing"
+		steps, err = alice.Undo(1)
+		require.NoError(t, err)
+		require.Len(t, steps, 1)
+		assert.Equal(t, int64(1), steps[0].TaskID)
+	})
+}
+
+func TestJSONStoreConcurrentCreate(t *testing.T) {
+	base := newTestJSONStore(t)
+	const workers, perWorker = 4, 10
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// a separate store per worker behaves like a separate process: own lock file descriptor
+			s := NewJSONStore(base.TaskDir, base.IndexDir, base.LastIDPath)
+			for j := 0; j < perWorker; j++ {
+				assert.NoError(t, s.Create(&types.Task{Description: "concurrent"}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	arr, err := base.List()
+	require.NoError(t, err)
+	require.Len(t, arr, workers*perWorker)
+	for i, elem := range arr {
+		assert.Equal(t, int64(i+1), elem.ID)
+	}
+}
+
+func TestJSONStoreConcurrentUpdate(t *testing.T) {
+	base := newTestJSONStore(t)
+	require.NoError(t, base.Create(&types.Task{Description: "contested"}))
+	const workers, perWorker = 4, 10
+
+	// every worker adds its own tags, reading the task again when it lost a race
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewJSONStore(base.TaskDir, base.IndexDir, base.LastIDPath)
+			for j := 0; j < perWorker; j++ {
+				for {
+					res, err := s.Get(1)
+					require.NoError(t, err)
+					res.Tags = append(res.Tags, fmt.Sprintf("w%d-%d", i, j))
+					err = s.Update(res)
+					if errors.Is(err, ErrStale) {
+						continue
+					}
+					require.NoError(t, err)
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	res, err := base.Get(1)
+	require.NoError(t, err)
+	assert.Len(t, res.Tags, workers*perWorker, "no update was lost")
+	assert.Equal(t, int64(workers*perWorker), res.Revision)
+}
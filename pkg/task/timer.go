@@ -0,0 +1,103 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+var (
+	// ErrNoTimer is returned by Stop when the user has no timer running.
+	ErrNoTimer = errors.New("no timer running")
+	// ErrTimerRunning is returned when a timer is started on a task on which the timer
+	// of another user runs, and when a task with a running timer is removed.
+	ErrTimerRunning = errors.New("timer running")
+)
+
+// Running returns the task on which the timer of user runs, nil when none does. A
+// task's timer is kept in Task.Timer, so it survives between runs of the program. A user
+// has one timer whatever the project, so a ProjectStore is looked through, and the
+// trash is searched too.
+func Running(s Store, user string) (*types.Task, error) {
+	s = unwrap(s)
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	trashed, err := s.Trash()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range append(all, trashed...) {
+		if t.Timer != nil && t.Timer.User == user {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+// Start starts the timer of user on the task with the given id and moves the task to
+// in_progress, in one Update. A user has one timer: a timer running on another task is
+// stopped first, that task is returned.
+func Start(s Store, id int64, user string, now time.Time) (stopped *types.Task, err error) {
+	t, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Timer != nil {
+		if t.Timer.User == user {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %s's timer runs on task %d", ErrTimerRunning, t.Timer.User, id)
+	}
+	if t.Status != types.StatusInProgress && !types.CanTransition(t.Status, types.StatusInProgress) {
+		return nil, fmt.Errorf("%w: task %d can not go from %s to %s", ErrTransition, t.ID, t.Status, types.StatusInProgress)
+	}
+
+	running, err := Running(s, user)
+	if err != nil {
+		return nil, err
+	}
+	if running != nil {
+		if stopped, _, err = Stop(s, user, now); err != nil {
+			return nil, err
+		}
+		if t, err = s.Get(id); err != nil {
+			return stopped, err
+		}
+	}
+	t.Status = types.StatusInProgress
+	t.Timer = &types.Interval{User: user, Start: now}
+	t.UpdateAt = now
+	return stopped, Save(s, t, false)
+}
+
+// Stop stops the timer of user and records its interval in the time log of the task,
+// which may be in another project than the one s is limited to. A timer on a task in the
+// trash, which Remove no longer lets happen, is only stopped after restoring the task.
+func Stop(s Store, user string, now time.Time) (*types.Task, types.Interval, error) {
+	t, err := Running(s, user)
+	if err != nil {
+		return nil, types.Interval{}, err
+	}
+	if t == nil {
+		return nil, types.Interval{}, ErrNoTimer
+	}
+	if t.Deleted() {
+		return nil, types.Interval{}, fmt.Errorf("%w: your timer runs on task %d in the trash, restore it to stop the timer", ErrTimerRunning, t.ID)
+	}
+	s = unwrap(s)
+	iv := *t.Timer
+	iv.End = now
+	if iv.End.Before(iv.Start) {
+		iv.End = iv.Start
+	}
+	t.TimeLog = append(t.TimeLog, iv)
+	t.Timer = nil
+	t.UpdateAt = now
+	if err := s.Update(t); err != nil {
+		return nil, types.Interval{}, err
+	}
+	return t, iv, nil
+}
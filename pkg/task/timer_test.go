@@ -0,0 +1,137 @@
+package task
+
+import (
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimer(t *testing.T) {
+	s := newTestJSONStore(t)
+	for _, desc := range []string{"invoices", "deploy", "cancelled"} {
+		require.NoError(t, s.Create(&types.Task{Description: desc}))
+	}
+	_, err := Mark(s, 3, types.StatusCancelled, false)
+	require.NoError(t, err)
+	now := time.Date(2026, time.October, 12, 9, 0, 0, 0, time.Local)
+
+	t.Run("start sets the task in progress", func(t *testing.T) {
+		stopped, err := Start(s, 1, "alice", now)
+		require.NoError(t, err)
+		assert.Nil(t, stopped)
+		got, err := s.Get(1)
+		require.NoError(t, err)
+		assert.Equal(t, types.StatusInProgress, got.Status)
+		require.NotNil(t, got.Timer)
+		assert.Equal(t, "alice", got.Timer.User)
+		assert.True(t, got.Timer.Start.Equal(now))
+
+		running, err := Running(s, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), running.ID)
+		running, err = Running(s, "bob")
+		require.NoError(t, err)
+		assert.Nil(t, running)
+	})
+
+	t.Run("one timer per task and per user", func(t *testing.T) {
+		_, err := Start(s, 1, "bob", now)
+		require.ErrorIs(t, err, ErrTimerRunning)
+		_, err = Start(s, 3, "alice", now)
+		require.ErrorIs(t, err, ErrTransition)
+
+		stopped, err := Start(s, 2, "alice", now.Add(time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, stopped)
+		assert.Equal(t, int64(1), stopped.ID)
+		assert.Equal(t, time.Hour, stopped.TimeSpent())
+		assert.Nil(t, stopped.Timer)
+	})
+
+	t.Run("stop records the interval", func(t *testing.T) {
+		got, iv, err := Stop(s, "alice", now.Add(90*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), got.ID)
+		assert.Equal(t, "alice", iv.User)
+		assert.True(t, iv.Start.Equal(now.Add(time.Hour)))
+		assert.Equal(t, 30*time.Minute, iv.Duration(iv.End))
+
+		got, err = s.Get(2)
+		require.NoError(t, err)
+		assert.Nil(t, got.Timer)
+		assert.Equal(t, 30*time.Minute, got.TimeSpent())
+		_, _, err = Stop(s, "alice", now)
+		require.ErrorIs(t, err, ErrNoTimer)
+
+		events, err := s.History(2)
+		require.NoError(t, err)
+		assert.Contains(t, events[len(events)-1].Changes, types.Change{Field: "time_spent", New: "30m0s"})
+	})
+
+	t.Run("undo takes the interval back", func(t *testing.T) {
+		_, err := s.Undo(1)
+		require.NoError(t, err)
+		running, err := Running(s, "alice")
+		require.NoError(t, err)
+		require.NotNil(t, running)
+		assert.Equal(t, int64(2), running.ID)
+		assert.Zero(t, running.TimeSpent())
+	})
+}
+
+func TestTimerProjectsAndTrash(t *testing.T) {
+	s := newTestJSONStore(t)
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, s.AddProject(&types.Project{Name: name}))
+	}
+	a, b := &ProjectStore{Store: s, Project: "a"}, &ProjectStore{Store: s, Project: "b"}
+	require.NoError(t, a.Create(&types.Task{Description: "in a"}))
+	require.NoError(t, b.Create(&types.Task{Description: "in b"}))
+	now := time.Date(2026, time.October, 12, 9, 0, 0, 0, time.Local)
+
+	t.Run("one timer across projects", func(t *testing.T) {
+		_, err := Start(a, 1, "alice", now)
+		require.NoError(t, err)
+		stopped, err := Start(b, 2, "alice", now.Add(time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, stopped, "the timer in project a was stopped")
+		assert.Equal(t, int64(1), stopped.ID)
+
+		got, _, err := Stop(a, "alice", now.Add(2*time.Hour))
+		require.NoError(t, err, "stopped from another project")
+		assert.Equal(t, int64(2), got.ID)
+		running, err := Running(s, "alice")
+		require.NoError(t, err)
+		assert.Nil(t, running)
+	})
+
+	t.Run("rm refuses a task with a running timer", func(t *testing.T) {
+		_, err := Start(s, 1, "alice", now)
+		require.NoError(t, err)
+		_, err = Remove(s, 1, false)
+		require.ErrorIs(t, err, ErrTimerRunning)
+		_, err = s.Get(1)
+		require.NoError(t, err, "still there")
+	})
+
+	t.Run("a timer in the trash still counts", func(t *testing.T) {
+		require.NoError(t, s.Delete(1)) // behind Remove's back
+		running, err := Running(s, "alice")
+		require.NoError(t, err)
+		require.NotNil(t, running)
+		_, _, err = Stop(s, "alice", now.Add(time.Hour))
+		require.ErrorIs(t, err, ErrTimerRunning)
+		_, err = Start(s, 2, "alice", now.Add(time.Hour))
+		require.ErrorIs(t, err, ErrTimerRunning, "no second timer")
+
+		require.NoError(t, s.Restore(1))
+		got, _, err := Stop(s, "alice", now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), got.ID)
+		_, err = Remove(s, 1, false)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,208 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"taskTracker/pkg/types"
+)
+
+var (
+	// ErrParent is returned for a parent that does not exist, is done or would make a task its own ancestor.
+	ErrParent = errors.New("invalid parent")
+	// ErrOpenSubtasks is returned when a task with open subtasks is marked done.
+	ErrOpenSubtasks = errors.New("task has open subtasks")
+	// ErrHasSubtasks is returned when a task with subtasks is removed without cascading.
+	ErrHasSubtasks = errors.New("task has subtasks")
+)
+
+// The rules of the task hierarchy need other tasks than the one being saved, so they are
+// checked here and not by the stores: Add, Save and Remove are what the command line and
+// the API call instead of Store.Create, Store.Update and Store.Delete. Save also checks
+// dependencies, see deps.go.
+//
+//   - a parent has to exist and a task can not become its own ancestor
+//   - a done task can not get open subtasks, neither new ones nor reopened ones
+//   - a task can only be marked done once its subtasks are done or cancelled
+//   - a task with subtasks is only removed together with them
+
+// Add checks the parent and the dependencies of t and creates it.
+func Add(s Store, t *types.Task) error {
+	if err := checkHierarchy(s, nil, t); err != nil {
+		return err
+	}
+	if err := checkDependencies(s, nil, t, false); err != nil {
+		return err
+	}
+	return s.Create(t)
+}
+
+// Save checks t against the stored version, the rules above and its dependencies and
+// updates it. force allows marking it done while tasks it depends on are still open.
+func Save(s Store, t *types.Task, force bool) error {
+	old, err := s.Get(t.ID)
+	if err != nil {
+		return err
+	}
+	if err := checkHierarchy(s, old, t); err != nil {
+		return err
+	}
+	if err := checkDependencies(s, old, t, force); err != nil {
+		return err
+	}
+	return s.Update(t)
+}
+
+func checkHierarchy(s Store, old, t *types.Task) error {
+	status := t.Status
+	if status == "" {
+		status = types.StatusTodo
+	}
+	parentChanged := old == nil || old.ParentID != t.ParentID
+	reopened := old == nil || (old.Status.Closed() && !status.Closed())
+	if old != nil && t.ParentID == t.ID {
+		return fmt.Errorf("%w: task %d can not be its own parent", ErrParent, t.ID)
+	}
+	if t.ParentID != 0 && (parentChanged || reopened) {
+		parent, err := s.Get(t.ParentID)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: task %d does not exist", ErrParent, t.ParentID)
+		}
+		if err != nil {
+			return err
+		}
+		if parent.Done() && !status.Closed() {
+			return fmt.Errorf("%w: task %d is done, reopen it first", ErrParent, parent.ID)
+		}
+		if old != nil && parentChanged {
+			if err := checkAncestors(s, t.ID, parent); err != nil {
+				return err
+			}
+		}
+	}
+	if old != nil && status == types.StatusDone && old.Status != types.StatusDone {
+		children, err := Children(s, t.ID)
+		if err != nil {
+			return err
+		}
+		for _, c := range children {
+			if !c.Status.Closed() {
+				return fmt.Errorf("%w: finish or cancel subtask %d of task %d first", ErrOpenSubtasks, c.ID, t.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAncestors fails when id is parent or one of its ancestors.
+func checkAncestors(s Store, id int64, parent *types.Task) error {
+	seen := map[int64]bool{}
+	for p := parent; ; {
+		if p.ID == id {
+			return fmt.Errorf("%w: task %d can not be a subtask of its own subtask %d", ErrParent, id, parent.ID)
+		}
+		if p.ParentID == 0 || seen[p.ID] {
+			return nil
+		}
+		seen[p.ID] = true
+		next, err := s.Get(p.ParentID)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p = next
+	}
+}
+
+// Children returns the direct subtasks of the task with the given id ordered by ID.
+func Children(s Store, id int64) ([]*types.Task, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*types.Task, 0)
+	for _, t := range all {
+		if t.ParentID == id {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// Remove moves the task with the given id to the trash. A task with subtasks fails with
+// ErrHasSubtasks unless cascade is set, then its whole subtree goes to the trash,
+// subtasks first. Nothing is removed while a timer runs on one of those tasks, that
+// fails with ErrTimerRunning. It returns the IDs of the removed tasks.
+func Remove(s Store, id int64, cascade bool) ([]int64, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	all, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int64][]int64)
+	for _, t := range all {
+		if t.ParentID != 0 {
+			children[t.ParentID] = append(children[t.ParentID], t.ID)
+		}
+	}
+	if len(children[id]) > 0 && !cascade {
+		return nil, fmt.Errorf("%w: remove the %d subtasks of task %d first or cascade", ErrHasSubtasks, len(children[id]), id)
+	}
+
+	// post-order, so a task is trashed only after its subtasks
+	var order []int64
+	seen := map[int64]bool{}
+	var visit func(id int64)
+	visit = func(id int64) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		for _, c := range children[id] {
+			visit(c)
+		}
+		order = append(order, id)
+	}
+	visit(id)
+	byID := make(map[int64]*types.Task, len(all))
+	for _, t := range all {
+		byID[t.ID] = t
+	}
+	for _, id := range order {
+		// a task removed in the meantime is not in all, Delete reports it below
+		if t, ok := byID[id]; ok && t.Timer != nil {
+			return nil, fmt.Errorf("%w: %s's timer runs on task %d, stop it first", ErrTimerRunning, t.Timer.User, id)
+		}
+	}
+
+	removed := make([]int64, 0, len(order))
+	for _, id := range order {
+		if err := s.Delete(id); err != nil {
+			return removed, err
+		}
+		removed = append(removed, id)
+	}
+	return removed, nil
+}
+
+// Progress counts the subtasks of every task in tasks that has any. Cancelled subtasks
+// are left out.
+func Progress(tasks []*types.Task) map[int64]types.Progress {
+	res := make(map[int64]types.Progress)
+	for _, t := range tasks {
+		if t.ParentID == 0 || t.Status == types.StatusCancelled {
+			continue
+		}
+		p := res[t.ParentID]
+		p.Total++
+		if t.Done() {
+			p.Done++
+		}
+		res[t.ParentID] = p
+	}
+	return res
+}
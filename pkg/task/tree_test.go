@@ -0,0 +1,96 @@
+package task
+
+import (
+	"os"
+	"taskTracker/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHierarchy(t *testing.T) {
+	s := NewMemStore()
+	parent := &types.Task{Description: "release"}
+	require.NoError(t, Add(s, parent))
+	first := &types.Task{Description: "changelog", ParentID: parent.ID}
+	second := &types.Task{Description: "tag", ParentID: parent.ID}
+	require.NoError(t, Add(s, first))
+	require.NoError(t, Add(s, second))
+	nested := &types.Task{Description: "draft", ParentID: first.ID}
+	require.NoError(t, Add(s, nested))
+
+	t.Run("parent has to exist", func(t *testing.T) {
+		require.ErrorIs(t, Add(s, &types.Task{Description: "x", ParentID: 99}), ErrParent)
+	})
+
+	t.Run("no cycles", func(t *testing.T) {
+		res, err := s.Get(parent.ID)
+		require.NoError(t, err)
+		res.ParentID = nested.ID
+		require.ErrorIs(t, Save(s, res, false), ErrParent)
+		res.ParentID = res.ID
+		require.ErrorIs(t, Save(s, res, false), ErrParent)
+	})
+
+	t.Run("done only after the subtasks", func(t *testing.T) {
+		_, err := Mark(s, parent.ID, types.StatusDone, false)
+		require.ErrorIs(t, err, ErrOpenSubtasks)
+
+		_, err = Mark(s, nested.ID, types.StatusDone, false)
+		require.NoError(t, err)
+		_, err = Mark(s, first.ID, types.StatusDone, false)
+		require.NoError(t, err)
+		_, err = Mark(s, second.ID, types.StatusCancelled, false)
+		require.NoError(t, err)
+		_, err = Mark(s, parent.ID, types.StatusDone, false)
+		require.NoError(t, err)
+	})
+
+	t.Run("a done parent gets no open subtasks", func(t *testing.T) {
+		require.ErrorIs(t, Add(s, &types.Task{Description: "late", ParentID: parent.ID}), ErrParent)
+		_, err := Mark(s, first.ID, types.StatusTodo, false)
+		require.ErrorIs(t, err, ErrParent)
+	})
+
+	t.Run("progress leaves cancelled subtasks out", func(t *testing.T) {
+		all, err := s.List()
+		require.NoError(t, err)
+		p := Progress(all)
+		assert.Equal(t, types.Progress{Done: 1, Total: 1}, p[parent.ID])
+		assert.Equal(t, types.Progress{Done: 1, Total: 1}, p[first.ID])
+		assert.Equal(t, "1/1 subtasks done", p[parent.ID].String())
+	})
+
+	t.Run("remove cascades only when asked", func(t *testing.T) {
+		_, err := Remove(s, parent.ID, false)
+		require.ErrorIs(t, err, ErrHasSubtasks)
+
+		removed, err := Remove(s, parent.ID, true)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{nested.ID, first.ID, second.ID, parent.ID}, removed)
+		_, err = s.Get(nested.ID)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("remove of a task removed meanwhile", func(t *testing.T) {
+		task := &types.Task{Description: "gone"}
+		require.NoError(t, Add(s, task))
+		_, err := Remove(removedAfterGet{s}, task.ID, false)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// removedAfterGet moves a task to the trash right after it was read, as another process
+// removing it at the same time would.
+type removedAfterGet struct {
+	Store
+}
+
+func (s removedAfterGet) Get(id int64) (*types.Task, error) {
+	t, err := s.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return t, s.Store.Delete(id)
+}
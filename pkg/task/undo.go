@@ -0,0 +1,465 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"taskTracker/pkg/types"
+	"taskTracker/pkg/utils"
+	"time"
+)
+
+// DefaultUndoLimit is how many changes JSONStore keeps for undo.
+const DefaultUndoLimit = 20
+
+// ErrUndoConflict is returned when the files of a step were changed by something that
+// bypassed the store, so restoring them would throw that change away.
+var ErrUndoConflict = errors.New("storage was changed outside of the undo history")
+
+// ErrUndoScope is returned when the latest change was made by another user, or under a
+// project to tasks outside of it: Undo and Redo take back the latest change only.
+var ErrUndoScope = errors.New("latest change was made by another user or outside the project")
+
+// UndoStep is one change of a JSONStore as it can be undone and redone: the task
+// records and year index entries it changed, and the content of any other file it
+// replaced (lastID, templates) before and after. The ID and full-text indexes are left
+// out, replaying a step stages them again for the tasks it restores.
+type UndoStep struct {
+	Op      string            `json:"op"`
+	TaskID  int64             `json:"task_id"`
+	At      time.Time         `json:"at"`
+	Event   types.Event       `json:"event"`
+	LogPath string            `json:"log_path"`
+	More    []StepEvent       `json:"more,omitempty"` // events of the other tasks the change updated
+	Tasks   []TaskChange      `json:"tasks,omitempty"`
+	Months  []MonthChange     `json:"months,omitempty"`
+	Before  []utils.FileWrite `json:"before,omitempty"`
+	After   []utils.FileWrite `json:"after,omitempty"`
+}
+
+// StepEvent is an event of an undo step and the audit log it was appended to.
+type StepEvent struct {
+	Event   types.Event `json:"event"`
+	LogPath string      `json:"log_path"`
+}
+
+// TaskChange is a task record of a month file before and after a change, nil where
+// the file did not hold it.
+type TaskChange struct {
+	File   string      `json:"file"`
+	ID     int64       `json:"id"`
+	Before *types.Task `json:"before,omitempty"`
+	After  *types.Task `json:"after,omitempty"`
+}
+
+// MonthChange is the first and last ID of a month in a year index before and after a
+// change, nil where the index had no entry for the month.
+type MonthChange struct {
+	File   string  `json:"file"`
+	Month  int     `json:"month"`
+	Before []int64 `json:"before,omitempty"`
+	After  []int64 `json:"after,omitempty"`
+}
+
+// undoHistory is kept in JSONStore.UndoPath, newest step last.
+type undoHistory struct {
+	Undo []UndoStep `json:"undo"`
+	Redo []UndoStep `json:"redo"`
+}
+
+func (s *JSONStore) readUndo() (*undoHistory, error) {
+	h := &undoHistory{}
+	data, err := os.ReadFile(s.UndoPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return h, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *JSONStore) writeUndo(tx *utils.Tx, h *undoHistory) error {
+	if over := len(h.Undo) - s.UndoLimit; over > 0 {
+		h.Undo = h.Undo[over:]
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tx.Write(s.UndoPath, data)
+	return nil
+}
+
+// commit appends ev to the audit log next to taskFile, records the change as the newest
+// undo step and commits tx. A new change drops the steps that could be redone.
+func (s *JSONStore) commit(tx *utils.Tx, taskFile string, ev types.Event) error {
+	return s.commitMany(tx, []string{taskFile}, []types.Event{ev})
+}
+
+// commitMany is commit for a change of several tasks, evs[i] is appended to the audit
+// log next to taskFiles[i]. The first event names the undo step.
+func (s *JSONStore) commitMany(tx *utils.Tx, taskFiles []string, evs []types.Event) error {
+	logs := make([]StepEvent, len(evs))
+	for i, ev := range evs {
+		logPath, err := logFile(s.TaskDir, s.LogDir, taskFiles[i])
+		if err != nil {
+			return err
+		}
+		logs[i] = StepEvent{Event: ev, LogPath: logPath}
+	}
+	if s.UndoLimit > 0 {
+		ev := evs[0]
+		step := UndoStep{Op: tx.Op, TaskID: ev.TaskID, At: ev.At, Event: ev, LogPath: logs[0].LogPath, More: logs[1:]}
+		if err := s.diff(tx, &step); err != nil {
+			return err
+		}
+		h, err := s.readUndo()
+		if err != nil {
+			return err
+		}
+		h.Undo = append(h.Undo, step)
+		h.Redo = nil
+		if err := s.writeUndo(tx, h); err != nil {
+			return err
+		}
+	}
+	for _, l := range logs {
+		if err := appendEvent(tx, l.LogPath, l.Event); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// diff records in step what tx changes: the task records of month files and the
+// entries of year indexes one by one, any other file whole. The ID and full-text
+// indexes follow from the tasks and are left out, they would make every step as big
+// as all tasks together.
+func (s *JSONStore) diff(tx *utils.Tx, step *UndoStep) error {
+	rest := utils.Begin(s.JournalPath, tx.Op)
+	for _, f := range tx.Files {
+		switch {
+		case f.Append || f.Path == idIndexFile(s.IndexDir) || f.Path == textIndexFile(s.IndexDir) || f.Path == textLogFile(s.IndexDir):
+		case s.isMonthFile(f.Path):
+			// records are compared as stored, only the ones that differ are decoded
+			data, err := os.ReadFile(f.Path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			before, err := rawTasks(f.Path, data)
+			if err != nil {
+				return err
+			}
+			after, err := rawTasks(f.Path, f.Content(data))
+			if err != nil {
+				return err
+			}
+			ids := make([]int64, 0, len(after))
+			for id := range before {
+				ids = append(ids, id)
+			}
+			for id := range after {
+				if _, ok := before[id]; !ok {
+					ids = append(ids, id)
+				}
+			}
+			slices.Sort(ids)
+			for _, id := range ids {
+				if bytes.Equal(before[id], after[id]) {
+					continue
+				}
+				c := TaskChange{File: f.Path, ID: id}
+				if c.Before, err = decodeRaw(before[id]); err != nil {
+					return err
+				}
+				if c.After, err = decodeRaw(after[id]); err != nil {
+					return err
+				}
+				if !sameTask(c.Before, c.After) {
+					step.Tasks = append(step.Tasks, c)
+				}
+			}
+		case filepath.Dir(f.Path) == filepath.Clean(s.IndexDir):
+			before, after := make(map[int][]int64), make(map[int][]int64)
+			if err := utils.DecodeIndex(f.Path, before); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if err := decodeIndex(tx, f.Path, after); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			for month := 1; month <= 12; month++ {
+				if !slices.Equal(before[month], after[month]) {
+					step.Months = append(step.Months, MonthChange{File: f.Path, Month: month, Before: before[month], After: after[month]})
+				}
+			}
+		default:
+			rest.Files = append(rest.Files, f)
+		}
+	}
+	before, err := rest.Snapshot()
+	if err != nil {
+		return err
+	}
+	step.Before, step.After = before, rest.Files
+	return nil
+}
+
+func (s *JSONStore) isMonthFile(fPath string) bool {
+	_, ok := monthOfFile(fPath)
+	return ok && filepath.Dir(filepath.Dir(fPath)) == filepath.Clean(s.TaskDir)
+}
+
+// rawTasks splits the content of a month file into the records of its tasks.
+func rawTasks(fPath string, data []byte) (map[int64]json.RawMessage, error) {
+	m := make(map[int64]json.RawMessage)
+	if len(data) == 0 {
+		return m, nil
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", fPath, err)
+	}
+	return m, nil
+}
+
+// decodeRaw decodes one record of rawTasks, nil stays nil.
+func decodeRaw(data json.RawMessage) (*types.Task, error) {
+	if data == nil {
+		return nil, nil
+	}
+	t := &types.Task{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// sameTask compares two records the way they are stored.
+func sameTask(a, b *types.Task) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	x, errX := json.Marshal(a)
+	y, errY := json.Marshal(b)
+	return errX == nil && errY == nil && bytes.Equal(x, y)
+}
+
+// Undo reverts up to n of the latest changes, newest first, and returns the steps it reverted.
+func (s *JSONStore) Undo(n int) ([]UndoStep, error) {
+	return s.replay(n, types.OpUndo)
+}
+
+// Redo applies again up to n changes reverted by Undo.
+func (s *JSONStore) Redo(n int) ([]UndoStep, error) {
+	return s.replay(n, types.OpRedo)
+}
+
+func (s *JSONStore) replay(n int, op string) ([]UndoStep, error) {
+	l, err := s.lockExclusive()
+	if err != nil {
+		return nil, err
+	}
+	defer l.Unlock()
+
+	res := make([]UndoStep, 0, n)
+	for len(res) < n {
+		h, err := s.readUndo()
+		if err != nil {
+			return res, err
+		}
+		from, to := &h.Undo, &h.Redo
+		if op == types.OpRedo {
+			from, to = to, from
+		}
+		if len(*from) == 0 {
+			break
+		}
+		step := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		*to = append(*to, step)
+
+		undo := op == types.OpUndo
+		if err := s.inScope(step); err != nil {
+			return res, fmt.Errorf("%w: can not %s %s of task %d, %v", ErrUndoScope, op, step.Op, step.TaskID, err)
+		}
+		if err := unchanged(step, undo); err != nil {
+			return res, fmt.Errorf("%w: can not %s %s of task %d: %v", ErrUndoConflict, op, step.Op, step.TaskID, err)
+		}
+
+		tx := utils.Begin(s.JournalPath, op)
+		if err := s.restore(tx, step, undo); err != nil {
+			return res, err
+		}
+		if err := s.writeUndo(tx, h); err != nil {
+			return res, err
+		}
+		for _, l := range append([]StepEvent{{Event: step.Event, LogPath: step.LogPath}}, step.More...) {
+			ev := types.Event{At: time.Now().Local(), Actor: s.Actor, Op: op, TaskID: l.Event.TaskID}
+			for _, c := range l.Event.Changes {
+				if undo {
+					c.Old, c.New = c.New, c.Old
+				}
+				ev.Changes = append(ev.Changes, c)
+			}
+			if err := appendEvent(tx, l.LogPath, ev); err != nil {
+				return res, err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return res, err
+		}
+		res = append(res, step)
+	}
+	return res, nil
+}
+
+// inScope fails unless step was made by s.Actor and, with UndoProject set, every task it
+// changed was in that project before or after the change.
+func (s *JSONStore) inScope(step UndoStep) error {
+	if step.Event.Actor != s.Actor {
+		return fmt.Errorf("it was made by %s", step.Event.Actor)
+	}
+	if s.UndoProject == "" {
+		return nil
+	}
+	for _, c := range step.Tasks {
+		if (c.Before == nil || c.Before.Project != s.UndoProject) && (c.After == nil || c.After.Project != s.UndoProject) {
+			return fmt.Errorf("task %d is not in project %s", c.ID, s.UndoProject)
+		}
+	}
+	return nil
+}
+
+// restore stages the files, task records and year index entries of step as they were
+// before it, or after it when undo is false, and the ID and full-text indexes to match.
+func (s *JSONStore) restore(tx *utils.Tx, step UndoStep, undo bool) error {
+	files := step.After
+	if undo {
+		files = step.Before
+	}
+	for _, f := range files {
+		if f.Remove {
+			tx.Remove(f.Path)
+		} else {
+			tx.Write(f.Path, f.Data)
+		}
+	}
+	for _, c := range step.Tasks {
+		put := pick(undo, c.Before, c.After)
+		tMap := make(map[int64]*types.Task)
+		if err := decodeTasks(tx, c.File, tMap); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if put == nil {
+			delete(tMap, c.ID)
+		} else {
+			tMap[c.ID] = put
+		}
+		if len(tMap) == 0 {
+			tx.Remove(c.File)
+		} else if err := tx.EncodeTasks(c.File, tMap); err != nil {
+			return err
+		}
+	}
+	for _, c := range step.Months {
+		put := pick(undo, c.Before, c.After)
+		iMap := make(map[int][]int64)
+		if err := decodeIndex(tx, c.File, iMap); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if put == nil {
+			delete(iMap, c.Month)
+		} else {
+			iMap[c.Month] = put
+		}
+		if len(iMap) == 0 {
+			tx.Remove(c.File)
+		} else if err := tx.EncodeIndex(c.File, iMap); err != nil {
+			return err
+		}
+	}
+	if len(step.Tasks) == 0 {
+		return nil
+	}
+
+	for _, c := range step.Tasks {
+		put := pick(undo, c.Before, c.After)
+		switch {
+		case put != nil:
+			loc, _ := monthOfFile(c.File)
+			if err := setID(tx, s.IndexDir, s.TaskDir, c.ID, loc); err != nil {
+				return err
+			}
+		case undo:
+			// the step created the task; a purged one keeps its ID, its audit log is found through it
+			if err := setID(tx, s.IndexDir, s.TaskDir, c.ID, idLocation{}); err != nil {
+				return err
+			}
+		}
+		if err := indexText(tx, s.IndexDir, s.TaskDir, c.ID, put); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pick returns what undoing, or else redoing, a step puts back.
+func pick[T any](undo bool, before, after T) T {
+	if undo {
+		return before
+	}
+	return after
+}
+
+// unchanged checks that the files, task records and year index entries still hold
+// what a step left in them, the state before it when undo is false.
+func unchanged(step UndoStep, undo bool) error {
+	files := step.Before
+	if undo {
+		files = step.After
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f.Path)
+		if f.Remove {
+			if err == nil {
+				return fmt.Errorf("%s exists", f.Path)
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, f.Data) {
+			return fmt.Errorf("%s differs", f.Path)
+		}
+	}
+	for _, c := range step.Tasks {
+		tMap := make(map[int64]*types.Task)
+		if err := utils.DecodeTasks(c.File, tMap); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !sameTask(tMap[c.ID], pick(undo, c.After, c.Before)) {
+			return fmt.Errorf("task %d in %s differs", c.ID, c.File)
+		}
+	}
+	for _, c := range step.Months {
+		iMap := make(map[int][]int64)
+		if err := utils.DecodeIndex(c.File, iMap); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !slices.Equal(iMap[c.Month], pick(undo, c.After, c.Before)) {
+			return fmt.Errorf("month %d in %s differs", c.Month, c.File)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operations recorded in the audit log.
+const (
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpDelete  = "delete" // moved to the trash
+	OpRestore = "restore"
+	OpPurge   = "purge"
+	OpUndo    = "undo"
+	OpRedo    = "redo"
+)
+
+// Event is one entry of the audit log: who changed which fields of a task and when.
+type Event struct {
+	At      time.Time `json:"at"`
+	Actor   string    `json:"actor,omitempty"`
+	Op      string    `json:"op"`
+	TaskID  int64     `json:"task_id"`
+	Changes []Change  `json:"changes,omitempty"`
+}
+
+// Change is the old and new value of one field, formatted as text. Field names follow
+// the json tags of Task, an empty value means the field was not set.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// auditFields are the fields compared by Diff. updated_at is left out, it changes
+// every time and Event.At already records it.
+var auditFields = []struct {
+	name  string
+	value func(t *Task) string
+}{
+	{"description", func(t *Task) string { return t.Description }},
+	{"status", func(t *Task) string { return string(t.Status) }},
+	{"priority", func(t *Task) string { return string(t.Priority) }},
+	{"due_at", func(t *Task) string { return formatTime(t.DueAt) }},
+	{"tags", func(t *Task) string { return strings.Join(t.Tags, ",") }},
+	{"project", func(t *Task) string { return t.Project }},
+	{"assignee", func(t *Task) string { return t.Assignee }},
+	{"estimate", func(t *Task) string { return FormatEstimate(t.Estimate) }},
+	{"created_by", func(t *Task) string { return t.CreatedBy }},
+	{"time_spent", func(t *Task) string { return formatSpent(t.TimeSpent()) }},
+	{"timer", func(t *Task) string { return formatTimer(t.Timer) }},
+	{"parent_id", func(t *Task) string { return formatID(t.ParentID) }},
+	{"depends_on", func(t *Task) string { return formatIDs(t.DependsOn) }},
+	{"created_at", func(t *Task) string { return formatTime(t.CreatedAt) }},
+	{"deleted_at", func(t *Task) string { return formatTime(t.DeletedAt) }},
+}
+
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func formatIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatSpent(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.Round(time.Second).String()
+}
+
+func formatTimer(iv *Interval) string {
+	if iv == nil {
+		return ""
+	}
+	return iv.User + " since " + formatTime(iv.Start)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Diff lists the fields that differ between old and updated. A nil task counts as
+// empty, so Diff(nil, t) lists everything set on a new task and Diff(t, nil) what a
+// deleted one held.
+func Diff(old, updated *Task) []Change {
+	if old == nil {
+		old = &Task{}
+	}
+	if updated == nil {
+		updated = &Task{}
+	}
+	var res []Change
+	for _, f := range auditFields {
+		o, n := f.value(old), f.value(updated)
+		if o != n {
+			res = append(res, Change{Field: f.name, Old: o, New: n})
+		}
+	}
+	return res
+}
+
+// NewEvent records op on a task going from old to updated. One of them may be nil.
+func NewEvent(op, actor string, old, updated *Task) Event {
+	ev := Event{At: time.Now().Local(), Actor: actor, Op: op, Changes: Diff(old, updated)}
+	if updated != nil {
+		ev.TaskID = updated.ID
+	} else if old != nil {
+		ev.TaskID = old.ID
+	}
+	return ev
+}
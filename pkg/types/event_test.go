@@ -0,0 +1,35 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	created := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	old := &Task{ID: 1, Description: "a", Status: StatusTodo, CreatedAt: created}
+
+	t.Run("update lists changed fields only", func(t *testing.T) {
+		updated := *old
+		updated.Status = StatusDone
+		updated.Tags = []string{"x", "y"}
+		updated.UpdateAt = created.Add(time.Hour)
+		assert.Equal(t, []Change{
+			{Field: "status", Old: "todo", New: "done"},
+			{Field: "tags", New: "x,y"},
+		}, Diff(old, &updated))
+	})
+
+	t.Run("create and delete", func(t *testing.T) {
+		assert.Contains(t, Diff(nil, old), Change{Field: "description", New: "a"})
+		assert.Contains(t, Diff(old, nil), Change{Field: "created_at", Old: "2026-10-01T09:00:00Z"})
+		assert.Empty(t, Diff(old, old))
+	})
+
+	t.Run("event takes the id of either side", func(t *testing.T) {
+		assert.Equal(t, int64(1), NewEvent(OpDelete, "me", old, nil).TaskID)
+		assert.Equal(t, int64(1), NewEvent(OpCreate, "me", nil, old).TaskID)
+	})
+}
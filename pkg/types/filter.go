@@ -2,10 +2,16 @@ package types
 
 import "time"
 
+// Filter selects tasks by creation date. Zero Day, Month or Year match any value.
+// From and To limit the creation date to a range of whole days, both ends included;
+// a zero From or To leaves that side open. Tasks in the trash only match with Trashed.
 type Filter struct {
-	Day   int
-	Month int
-	Year  int
+	Day     int
+	Month   int
+	Year    int
+	From    time.Time
+	To      time.Time
+	Trashed bool
 }
 
 func NewFilter() *Filter {
@@ -16,3 +22,63 @@ func NewFilter() *Filter {
 		Year:  y,
 	}
 }
+
+// DateLayout is how dates are written on the command line and in query strings.
+const DateLayout = "2006-01-02"
+
+// ParseDate parses a DateLayout date in local time.
+func ParseDate(s string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, s, time.Local)
+}
+
+// MatchTask reports whether t passes the filter.
+func (f *Filter) MatchTask(t *Task) bool {
+	if t.Deleted() && !f.Trashed {
+		return false
+	}
+	return f.Match(t.CreatedAt)
+}
+
+// Match reports whether a task created at t passes the filter.
+func (f *Filter) Match(t time.Time) bool {
+	y, m, d := t.Date()
+	if f.Year > 0 && y != f.Year {
+		return false
+	}
+	if f.Month > 0 && int(m) != f.Month {
+		return false
+	}
+	if f.Day > 0 && d != f.Day {
+		return false
+	}
+	if !f.From.IsZero() && t.Before(startOfDay(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && !t.Before(startOfDay(f.To).AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// MatchMonth reports whether any task created in month m of year y can pass the filter.
+func (f *Filter) MatchMonth(y int, m time.Month) bool {
+	if f.Year > 0 && y != f.Year {
+		return false
+	}
+	if f.Month > 0 && int(m) != f.Month {
+		return false
+	}
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.Local)
+	if !f.From.IsZero() && !first.AddDate(0, 1, 0).After(startOfDay(f.From)) {
+		return false
+	}
+	if !f.To.IsZero() && first.After(startOfDay(f.To)) {
+		return false
+	}
+	return true
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
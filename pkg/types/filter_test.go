@@ -0,0 +1,52 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func TestFilterMatch(t *testing.T) {
+	at := time.Date(2026, time.March, 15, 23, 59, 0, 0, time.Local)
+
+	t.Run("day month year", func(t *testing.T) {
+		assert.True(t, (&Filter{Year: 2026, Month: 3, Day: 15}).Match(at))
+		assert.True(t, (&Filter{Year: 2026, Month: 3}).Match(at))
+		assert.True(t, (&Filter{}).Match(at))
+		assert.False(t, (&Filter{Year: 2026, Month: 3, Day: 14}).Match(at))
+		assert.False(t, (&Filter{Year: 2025}).Match(at))
+	})
+
+	t.Run("range includes both days", func(t *testing.T) {
+		assert.True(t, (&Filter{From: date(2026, 3, 15), To: date(2026, 3, 15)}).Match(at))
+		assert.True(t, (&Filter{From: date(2025, 12, 1)}).Match(at))
+		assert.True(t, (&Filter{To: date(2026, 3, 15)}).Match(at))
+		assert.False(t, (&Filter{From: date(2026, 3, 16)}).Match(at))
+		assert.False(t, (&Filter{To: date(2026, 3, 14)}).Match(at))
+	})
+}
+
+func TestFilterMatchMonth(t *testing.T) {
+	f := &Filter{From: date(2025, 11, 20), To: date(2026, 2, 3)}
+	assert.False(t, f.MatchMonth(2025, time.October))
+	assert.True(t, f.MatchMonth(2025, time.November))
+	assert.True(t, f.MatchMonth(2026, time.January))
+	assert.True(t, f.MatchMonth(2026, time.February))
+	assert.False(t, f.MatchMonth(2026, time.March))
+
+	assert.False(t, (&Filter{Year: 2026, Month: 5}).MatchMonth(2026, time.April))
+}
+
+func TestParseDate(t *testing.T) {
+	d, err := ParseDate("2026-03-15")
+	require.NoError(t, err)
+	assert.True(t, d.Equal(date(2026, 3, 15)))
+	_, err = ParseDate("15.03.2026")
+	require.Error(t, err)
+}
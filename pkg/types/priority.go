@@ -0,0 +1,66 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority of a task. The empty value is read as PriorityNormal.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities lists all priorities from the lowest to the highest.
+var Priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+func ParsePriority(s string) (Priority, error) {
+	for _, p := range Priorities {
+		if strings.EqualFold(string(p), s) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown priority %q, expected one of %v", s, Priorities)
+}
+
+// Rank orders priorities: low is 0, urgent is 3. Unknown values rank as normal.
+func (p Priority) Rank() int {
+	for i, elem := range Priorities {
+		if elem == p {
+			return i
+		}
+	}
+	return 1
+}
+
+// OrDefault returns PriorityNormal for the empty priority.
+func (p Priority) OrDefault() Priority {
+	if p == "" {
+		return PriorityNormal
+	}
+	return p
+}
+
+// NormalizeTags trims and lowercases tags and drops empty and repeated ones.
+func NormalizeTags(tags []string) []string {
+	res := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		res = append(res, tag)
+	}
+	return res
+}
+
+// SplitTags parses a comma separated list of tags.
+func SplitTags(s string) []string {
+	return NormalizeTags(strings.Split(s, ","))
+}
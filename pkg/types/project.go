@@ -0,0 +1,31 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Project groups the tasks of, e.g., one service. A task names its project in
+// Task.Project; IDs stay unique across all projects.
+type Project struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ParseProjectName checks a project name: lower case letters, digits, '-', '_' and '.',
+// so it works unquoted on the command line and in queries. Upper case is folded.
+func ParseProjectName(s string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" {
+		return "", fmt.Errorf("empty project name")
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.", r) {
+			return "", fmt.Errorf("project name %q: use letters, digits, '-', '_' or '.'", s)
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,134 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseEstimate parses an estimate, a number of points or hours that is not negative.
+// none or an empty string removes the estimate.
+func ParseEstimate(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return 0, nil
+	}
+	e, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("estimate %q is not a number of points or hours", s)
+	}
+	return e, CheckEstimate(e)
+}
+
+// CheckEstimate fails for negative estimates and ones that are not finite.
+func CheckEstimate(e float64) error {
+	if e < 0 || math.IsInf(e, 0) || math.IsNaN(e) {
+		return fmt.Errorf("estimate %v is not a number of points or hours", e)
+	}
+	return nil
+}
+
+// FormatEstimate writes an estimate without trailing zeros, leaving 0 empty.
+func FormatEstimate(e float64) string {
+	if e == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(e, 'f', -1, 64)
+}
+
+// CompletedAt is when the task was marked done, zero while it is not done. Tasks marked
+// done before DoneAt was recorded fall back to their last update.
+func (t *Task) CompletedAt() time.Time {
+	if !t.Done() {
+		return time.Time{}
+	}
+	if t.DoneAt.IsZero() {
+		return t.UpdateAt
+	}
+	return t.DoneAt
+}
+
+// Stats sums up the tasks created and completed in a range of days.
+type Stats struct {
+	From      string `json:"from"` // DateLayout, first day
+	To        string `json:"to"`   // last day
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	// CompletionRate is the share of the tasks created in the range that are done at its end.
+	CompletionRate float64 `json:"completion_rate"`
+	// LeadTimeSeconds is the average time from creation to done of the tasks completed
+	// in the range.
+	LeadTimeSeconds int64         `json:"lead_time_seconds"`
+	Burndown        []BurndownDay `json:"burndown"`
+}
+
+func (s Stats) LeadTime() time.Duration {
+	return time.Duration(s.LeadTimeSeconds) * time.Second
+}
+
+// BurndownDay is the work left at the end of a day.
+type BurndownDay struct {
+	Day       string  `json:"day"` // DateLayout
+	Created   int     `json:"created"`
+	Completed int     `json:"completed"`
+	Open      int     `json:"open"`     // tasks not done at the end of the day
+	Estimate  float64 `json:"estimate"` // the estimates of the open tasks summed up
+}
+
+// ComputeStats counts the tasks created and completed between from and to, whole local
+// days with to excluded, and the open tasks at the end of every day up to now. tasks
+// has to hold every task created before to. Cancelled tasks count as created but are
+// never open or completed.
+func ComputeStats(tasks []*Task, from, to, now time.Time) Stats {
+	st := Stats{From: from.Format(DateLayout), To: to.AddDate(0, 0, -1).Format(DateLayout), Burndown: []BurndownDay{}}
+	in := func(t time.Time) bool { return !t.IsZero() && !t.Before(from) && t.Before(to) }
+
+	var createdDone int
+	var lead time.Duration
+	for _, t := range tasks {
+		done := t.CompletedAt()
+		if in(t.CreatedAt) {
+			st.Created++
+			if !done.IsZero() && done.Before(to) {
+				createdDone++
+			}
+		}
+		if in(done) {
+			st.Completed++
+			lead += max(done.Sub(t.CreatedAt), 0)
+		}
+	}
+	if st.Created > 0 {
+		st.CompletionRate = float64(createdDone) / float64(st.Created)
+	}
+	if st.Completed > 0 {
+		st.LeadTimeSeconds = int64(lead / time.Duration(st.Completed) / time.Second)
+	}
+
+	for day := from; day.Before(to) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		bd := BurndownDay{Day: day.Format(DateLayout)}
+		for _, t := range tasks {
+			if !t.CreatedAt.Before(end) {
+				continue
+			}
+			done := t.CompletedAt()
+			if !t.CreatedAt.Before(day) {
+				bd.Created++
+			}
+			switch {
+			case !done.IsZero() && done.Before(end):
+				if !done.Before(day) {
+					bd.Completed++
+				}
+			case t.Status != StatusCancelled:
+				bd.Open++
+				bd.Estimate += t.Estimate
+			}
+		}
+		st.Burndown = append(st.Burndown, bd)
+	}
+	return st
+}
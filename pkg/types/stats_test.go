@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeStats(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, time.October, day, hour, 0, 0, 0, time.Local)
+	}
+	tasks := []*Task{
+		{ID: 1, CreatedAt: at(10, 9), Status: StatusDone, DoneAt: at(13, 9), Estimate: 3},     // before the range, done in it
+		{ID: 2, CreatedAt: at(12, 9), Status: StatusDone, DoneAt: at(12, 21), Estimate: 1},    // created and done on the first day
+		{ID: 3, CreatedAt: at(12, 10), Status: StatusInProgress, Estimate: 5},                 // open
+		{ID: 4, CreatedAt: at(13, 10), Status: StatusCancelled, Estimate: 8},                  // never open
+		{ID: 5, CreatedAt: at(13, 11), Status: StatusDone, UpdateAt: at(14, 11), Estimate: 2}, // done before DoneAt existed
+		{ID: 6, CreatedAt: at(14, 12), Status: StatusDone, DoneAt: at(16, 12)},                // done after the range
+	}
+
+	got := ComputeStats(tasks, at(12, 0), at(15, 0), at(14, 15))
+	assert.Equal(t, "2026-10-12", got.From)
+	assert.Equal(t, "2026-10-14", got.To)
+	assert.Equal(t, 5, got.Created)
+	assert.Equal(t, 3, got.Completed)
+	assert.InDelta(t, 0.4, got.CompletionRate, 1e-9, "tasks 2 and 5 of the 5 created are done")
+	assert.Equal(t, (72+12+24)*time.Hour/3, got.LeadTime())
+	assert.Equal(t, []BurndownDay{
+		{Day: "2026-10-12", Created: 2, Completed: 1, Open: 2, Estimate: 8},
+		{Day: "2026-10-13", Created: 2, Completed: 1, Open: 2, Estimate: 7},
+		{Day: "2026-10-14", Created: 1, Completed: 1, Open: 2, Estimate: 5},
+	}, got.Burndown)
+
+	got = ComputeStats(tasks, at(14, 0), at(21, 0), at(14, 15))
+	assert.Len(t, got.Burndown, 1, "no days after now")
+	got = ComputeStats(nil, at(1, 0), at(2, 0), at(14, 15))
+	assert.Zero(t, got.CompletionRate)
+	assert.Zero(t, got.LeadTime())
+}
+
+func TestParseEstimate(t *testing.T) {
+	for in, want := range map[string]float64{"3": 3, " 2.5 ": 2.5, "0": 0, "none": 0, "": 0} {
+		e, err := ParseEstimate(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, e, in)
+	}
+	for _, in := range []string{"-1", "three", "NaN", "Inf"} {
+		_, err := ParseEstimate(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Status is the workflow state of a task.
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+var Statuses = []Status{StatusTodo, StatusInProgress, StatusBlocked, StatusDone, StatusCancelled}
+
+// transitions lists where a task may go from each status. Staying in the same status is always allowed.
+var transitions = map[Status][]Status{
+	StatusTodo:       {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusTodo, StatusBlocked, StatusDone, StatusCancelled},
+	StatusBlocked:    {StatusTodo, StatusInProgress, StatusCancelled},
+	StatusDone:       {StatusTodo, StatusInProgress},
+	StatusCancelled:  {StatusTodo},
+}
+
+func ParseStatus(s string) (Status, error) {
+	for _, st := range Statuses {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	// accept "in-progress" and "inprogress" for convenience
+	switch s {
+	case "in-progress", "inprogress":
+		return StatusInProgress, nil
+	}
+	return "", fmt.Errorf("unknown status %q, expected one of %v", s, Statuses)
+}
+
+// CanTransition reports whether a task may move from status from to status to.
+func CanTransition(from, to Status) bool {
+	return from == to || slices.Contains(transitions[from], to)
+}
+
+// Closed reports whether no more work is expected on a task in this status.
+func (s Status) Closed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
@@ -1,11 +1,81 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
+// Task is stored as json. Fields added after the first release are optional,
+// so older month files keep decoding.
 type Task struct {
-	CreatedAt   time.Time `json:"created_at"`
-	UpdateAt    time.Time `json:"updated_at"`
-	Description string    `json:"description"`
-	ID          int64     `json:"id"`
-	Done        bool      `json:"done"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdateAt    time.Time  `json:"updated_at"`
+	Revision    int64      `json:"revision,omitempty"` // how often the task was updated, see task.ErrStale
+	DueAt       time.Time  `json:"due_at,omitzero"`
+	DoneAt      time.Time  `json:"done_at,omitzero"` // when the task was last marked done
+	Description string     `json:"description"`
+	Priority    Priority   `json:"priority,omitempty"`
+	Status      Status     `json:"status"`
+	Tags        []string   `json:"tags,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	Assignee    string     `json:"assignee,omitempty"`
+	Estimate    float64    `json:"estimate,omitempty"` // points or hours, as the team counts
+	TimeLog     []Interval `json:"time_log,omitempty"`
+	Timer       *Interval  `json:"timer,omitempty"` // the running timer, see Interval
+	DeletedAt   time.Time  `json:"deleted_at,omitzero"`
+	ParentID    int64      `json:"parent_id,omitempty"`
+	DependsOn   []int64    `json:"depends_on,omitempty"`
+	ID          int64      `json:"id"`
+}
+
+// Done reports whether the task is finished.
+func (t *Task) Done() bool {
+	return t.Status == StatusDone
+}
+
+// Deleted reports whether the task is in the trash.
+func (t *Task) Deleted() bool {
+	return !t.DeletedAt.IsZero()
+}
+
+// Progress counts the direct subtasks of a task. Cancelled subtasks are not counted.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%d/%d subtasks done", p.Done, p.Total)
+}
+
+// taskAlias has the fields of Task without its json methods.
+type taskAlias Task
+
+// MarshalJSON keeps writing the "done" flag that came before Status, so older
+// readers of the files and of the API still see whether a task is finished.
+func (t Task) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		taskAlias
+		Done bool `json:"done"`
+	}{taskAlias: taskAlias(t), Done: t.Done()})
+}
+
+// UnmarshalJSON maps the "done" flag of tasks written before Status existed.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*taskAlias
+		Done bool `json:"done"`
+	}{taskAlias: (*taskAlias)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if t.Status == "" {
+		t.Status = StatusTodo
+		if aux.Done {
+			t.Status = StatusDone
+		}
+	}
+	return nil
 }
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskJSON(t *testing.T) {
+	t.Run("files written before priority, due date and tags still decode", func(t *testing.T) {
+		old := `{"created_at":"2025-07-01T10:00:00Z","updated_at":"2025-07-01T10:00:00Z","description":"old","id":3,"done":true}`
+		res := Task{}
+		require.NoError(t, json.Unmarshal([]byte(old), &res))
+		assert.Equal(t, int64(3), res.ID)
+		assert.Equal(t, StatusDone, res.Status)
+		assert.Equal(t, PriorityNormal, res.Priority.OrDefault())
+		assert.True(t, res.DueAt.IsZero())
+		assert.Empty(t, res.Tags)
+	})
+
+	t.Run("done without status maps to todo", func(t *testing.T) {
+		res := Task{}
+		require.NoError(t, json.Unmarshal([]byte(`{"id":1,"done":false}`), &res))
+		assert.Equal(t, StatusTodo, res.Status)
+	})
+
+	t.Run("status wins over done and done is still written", func(t *testing.T) {
+		res := Task{}
+		require.NoError(t, json.Unmarshal([]byte(`{"id":1,"status":"blocked","done":false}`), &res))
+		assert.Equal(t, StatusBlocked, res.Status)
+
+		data, err := json.Marshal(Task{ID: 1, Status: StatusDone})
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"status":"done"`)
+		assert.Contains(t, string(data), `"done":true`)
+	})
+
+	t.Run("unset optional fields are left out", func(t *testing.T) {
+		data, err := json.Marshal(Task{ID: 1, CreatedAt: time.Now()})
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "due_at")
+		assert.NotContains(t, string(data), "priority")
+		assert.NotContains(t, string(data), "tags")
+	})
+}
+
+func TestPriority(t *testing.T) {
+	p, err := ParsePriority("HIGH")
+	require.NoError(t, err)
+	assert.Equal(t, PriorityHigh, p)
+	_, err = ParsePriority("asap")
+	assert.Error(t, err)
+
+	assert.Less(t, PriorityLow.Rank(), Priority("").Rank())
+	assert.Equal(t, PriorityNormal.Rank(), Priority("").Rank())
+	assert.Less(t, PriorityHigh.Rank(), PriorityUrgent.Rank())
+}
+
+func TestSplitTags(t *testing.T) {
+	assert.Equal(t, []string{"ops", "billing"}, SplitTags(" Ops,billing,,ops "))
+	assert.Empty(t, SplitTags(""))
+}
+
+func TestCanTransition(t *testing.T) {
+	assert.True(t, CanTransition(StatusTodo, StatusInProgress))
+	assert.True(t, CanTransition(StatusDone, StatusTodo))
+	assert.True(t, CanTransition(StatusBlocked, StatusBlocked))
+	assert.False(t, CanTransition(StatusCancelled, StatusDone))
+	assert.False(t, CanTransition(StatusBlocked, StatusDone))
+
+	st, err := ParseStatus("in-progress")
+	require.NoError(t, err)
+	assert.Equal(t, StatusInProgress, st)
+	_, err = ParseStatus("finished")
+	assert.Error(t, err)
+}
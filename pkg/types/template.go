@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// Template describes a recurring task. Every occurrence of Schedule (see package recur)
+// becomes a task with the description, priority, tags and project of the template. Last is the
+// latest occurrence a task was created for, so no occurrence is created twice.
+type Template struct {
+	ID          int64     `json:"id"`
+	Description string    `json:"description"`
+	Priority    Priority  `json:"priority,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	Schedule    string    `json:"schedule"`
+	CreatedAt   time.Time `json:"created_at"`
+	Last        time.Time `json:"last,omitzero"`
+}
@@ -0,0 +1,95 @@
+package types
+
+import (
+	"sort"
+	"time"
+)
+
+// Interval is time User spent on a task. End is zero while the timer runs.
+type Interval struct {
+	User  string    `json:"user,omitempty"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end,omitzero"`
+}
+
+// Duration is the length of the interval, up to now while it runs.
+func (iv Interval) Duration(now time.Time) time.Duration {
+	end := iv.End
+	if end.IsZero() {
+		end = now
+	}
+	return max(end.Sub(iv.Start), 0)
+}
+
+// TimeSpent sums the recorded intervals of the task, a running timer left out.
+func (t *Task) TimeSpent() time.Duration {
+	var d time.Duration
+	for _, iv := range t.TimeLog {
+		d += iv.Duration(iv.End)
+	}
+	return d
+}
+
+// TimeEntry is the time spent on one task on one day.
+type TimeEntry struct {
+	Day         string `json:"day"` // DateLayout
+	TaskID      int64  `json:"task_id"`
+	Project     string `json:"project,omitempty"`
+	Description string `json:"description"`
+	Seconds     int64  `json:"seconds"`
+}
+
+func (e TimeEntry) Spent() time.Duration {
+	return time.Duration(e.Seconds) * time.Second
+}
+
+// TimeReport adds up the time spent on tasks per task and local day, counting the part
+// of every interval between from and to. Running timers count up to now. Entries are
+// ordered by day and task ID.
+func TimeReport(tasks []*Task, from, to, now time.Time) []TimeEntry {
+	type key struct {
+		day string
+		id  int64
+	}
+	spent := make(map[key]time.Duration)
+	byID := make(map[int64]*Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+		intervals := t.TimeLog
+		if t.Timer != nil {
+			intervals = append(intervals[:len(intervals):len(intervals)], *t.Timer)
+		}
+		for _, iv := range intervals {
+			start, end := iv.Start, iv.Start.Add(iv.Duration(now))
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			// split at midnight so every day gets its part
+			for start.Before(end) {
+				y, m, d := start.Date()
+				next := time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+				if next.After(end) {
+					next = end
+				}
+				spent[key{start.Format(DateLayout), t.ID}] += next.Sub(start)
+				start = next
+			}
+		}
+	}
+
+	res := make([]TimeEntry, 0, len(spent))
+	for k, d := range spent {
+		t := byID[k.id]
+		res = append(res, TimeEntry{Day: k.day, TaskID: k.id, Project: t.Project, Description: t.Description, Seconds: int64(d / time.Second)})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Day != res[j].Day {
+			return res[i].Day < res[j].Day
+		}
+		return res[i].TaskID < res[j].TaskID
+	})
+	return res
+}
@@ -0,0 +1,37 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeReport(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, time.October, day, hour, min, 0, 0, time.Local)
+	}
+	tasks := []*Task{
+		{ID: 1, Description: "invoices", Project: "billing", TimeLog: []Interval{
+			{User: "alice", Start: at(12, 9, 0), End: at(12, 10, 30)},
+			{User: "bob", Start: at(12, 14, 0), End: at(12, 14, 45)},
+			{User: "alice", Start: at(13, 23, 0), End: at(14, 1, 0)}, // across midnight
+		}},
+		{ID: 2, Description: "deploy", Timer: &Interval{User: "alice", Start: at(14, 8, 0)}},
+		{ID: 3, Description: "untracked"},
+	}
+	assert.Equal(t, 2*time.Hour+15*time.Minute+2*time.Hour, tasks[0].TimeSpent())
+
+	now := at(14, 9, 30)
+	got := TimeReport(tasks, at(12, 0, 0), at(19, 0, 0), now)
+	assert.Equal(t, []TimeEntry{
+		{Day: "2026-10-12", TaskID: 1, Project: "billing", Description: "invoices", Seconds: 135 * 60},
+		{Day: "2026-10-13", TaskID: 1, Project: "billing", Description: "invoices", Seconds: 60 * 60},
+		{Day: "2026-10-14", TaskID: 1, Project: "billing", Description: "invoices", Seconds: 60 * 60},
+		{Day: "2026-10-14", TaskID: 2, Description: "deploy", Seconds: 90 * 60},
+	}, got, "a running timer counts up to now")
+
+	got = TimeReport(tasks, at(13, 23, 30), at(14, 0, 0), now)
+	assert.Equal(t, []TimeEntry{{Day: "2026-10-13", TaskID: 1, Project: "billing", Description: "invoices", Seconds: 30 * 60}}, got, "intervals are cut to the range")
+	assert.Empty(t, TimeReport(tasks, at(1, 0, 0), at(2, 0, 0), now))
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Run("creates and replaces", func(t *testing.T) {
+		fPath := filepath.Join(t.TempDir(), "5.json")
+		require.NoError(t, WriteFileAtomic(fPath, []byte("old")))
+		require.NoError(t, WriteFileAtomic(fPath, []byte("new")))
+		data, err := os.ReadFile(fPath)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+	})
+
+	t.Run("no temp files left behind", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, WriteFileAtomic(filepath.Join(dir, "5.json"), []byte("{}")))
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("missing directory -> error", func(t *testing.T) {
+		err := WriteFileAtomic("nonExistingDir/5.json", []byte("{}"))
+		require.Error(t, err)
+	})
+}
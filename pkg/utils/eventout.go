@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// WriteEvents writes the audit log of a task to w in format f. csv and table have one
+// row per changed field. width is only used by FormatTable.
+func WriteEvents(w io.Writer, f Format, events []types.Event, width int) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, ev := range events {
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"at", "actor", "op", "task_id", "field", "old", "new"})
+		for _, row := range eventRows(events, time.RFC3339) {
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTable:
+		rows := [][]string{{"AT", "ACTOR", "OP", "TASK_ID", "FIELD", "OLD", "NEW"}}
+		for _, row := range eventRows(events, tableTime) {
+			row[5] = strings.Join(strings.Fields(row[5]), " ")
+			row[6] = strings.Join(strings.Fields(row[6]), " ")
+			rows = append(rows, row)
+		}
+		return writeAligned(w, rows, width)
+	default:
+		for _, ev := range events {
+			ShowEvent(w, ev)
+		}
+		return nil
+	}
+}
+
+// eventRows flattens events into one row per change. Events without changes keep one row.
+func eventRows(events []types.Event, layout string) [][]string {
+	rows := make([][]string, 0, len(events))
+	for _, ev := range events {
+		head := []string{ev.At.Format(layout), ev.Actor, ev.Op, strconv.FormatInt(ev.TaskID, 10)}
+		if len(ev.Changes) == 0 {
+			rows = append(rows, append(head, "", "", ""))
+			continue
+		}
+		for _, c := range ev.Changes {
+			row := append(append([]string{}, head...), c.Field, c.Old, c.New)
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// ShowEvent prints one event and its changes, an update as "field: old -> new".
+func ShowEvent(w io.Writer, ev types.Event) {
+	fmt.Fprintf(w, "%v %v %v task %v\n", ev.At.Format(tableTime), ev.Actor, ev.Op, ev.TaskID)
+	for _, c := range ev.Changes {
+		switch ev.Op {
+		case types.OpCreate:
+			fmt.Fprintf(w, "    %v: %v\n", c.Field, c.New)
+		case types.OpPurge:
+			fmt.Fprintf(w, "    %v: %v\n", c.Field, c.Old)
+		default:
+			fmt.Fprintf(w, "    %v: %v -> %v\n", c.Field, orNone(c.Old), orNone(c.New))
+		}
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
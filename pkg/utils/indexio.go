@@ -36,14 +36,11 @@ func DecodeIndex(fPath string, dst map[int][]int64) error {
 	return nil
 }
 
+// EncodeIndex saves the index to json file. The file is replaced atomically.
 func EncodeIndex(fPath string, src map[int][]int64) error {
-	wFile, err := os.OpenFile(fPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	data, err := marshalJSON(&src)
 	if err != nil {
 		return err
 	}
-	defer wFile.Close()
-	if err := json.NewEncoder(wFile).Encode(&src); err != nil {
-		return err
-	}
-	return nil
+	return WriteFileAtomic(fPath, data)
 }
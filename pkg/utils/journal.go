@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"taskTracker/pkg/types"
+)
+
+// Tx collects file writes that have to land together, e.g. a month file, a year index
+// and lastID for one created task.
+//
+// Commit first writes all new contents into the journal file. The journal is written
+// like any other file: temp file, fsync, rename. The rename is the commit marker: once
+// the journal exists the transaction is committed and Recover will roll it forward,
+// before that none of the target files has been touched. Only then are the target
+// files replaced one by one and the journal removed.
+type Tx struct {
+	journalPath string
+	Op          string      `json:"op"`
+	Files       []FileWrite `json:"files"`
+}
+
+// FileWrite is the full new content of one file. Remove deletes the file instead.
+// Append writes Data at Offset, the size the file had when the write was staged, and
+// cuts off anything behind it, so rolling the journal forward twice appends only once.
+// At writes Data at Offset and keeps the rest of the file, for records of a fixed size.
+type FileWrite struct {
+	Path   string `json:"path"`
+	Data   []byte `json:"data,omitempty"`
+	Remove bool   `json:"remove,omitempty"`
+	Append bool   `json:"append,omitempty"`
+	At     bool   `json:"at,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+}
+
+// Content returns what the file holds after f, when it held base before.
+func (f FileWrite) Content(base []byte) []byte {
+	switch {
+	case f.Remove:
+		return nil
+	case f.Append, f.At:
+		end := f.Offset + int64(len(f.Data))
+		res := make([]byte, max(end, int64(len(base))))
+		copy(res, base)
+		copy(res[f.Offset:], f.Data)
+		if f.Append {
+			res = res[:end]
+		}
+		return res
+	default:
+		return f.Data
+	}
+}
+
+// Begin starts a transaction named op that will be journaled in journalPath.
+func Begin(journalPath, op string) *Tx {
+	return &Tx{journalPath: journalPath, Op: op}
+}
+
+// Write stages data as the new content of fPath. A later write to the same path wins.
+func (tx *Tx) Write(fPath string, data []byte) {
+	for i := range tx.Files {
+		if tx.Files[i].Path == fPath {
+			tx.Files[i] = FileWrite{Path: fPath, Data: data}
+			return
+		}
+	}
+	tx.Files = append(tx.Files, FileWrite{Path: fPath, Data: data})
+}
+
+// Remove stages removal of fPath.
+func (tx *Tx) Remove(fPath string) {
+	for i := range tx.Files {
+		if tx.Files[i].Path == fPath {
+			tx.Files[i] = FileWrite{Path: fPath, Remove: true}
+			return
+		}
+	}
+	tx.Files = append(tx.Files, FileWrite{Path: fPath, Remove: true})
+}
+
+// Append stages adding data to the end of fPath, which does not have to exist yet.
+// Several appends to the same path in one transaction are joined.
+func (tx *Tx) Append(fPath string, data []byte) error {
+	for i := range tx.Files {
+		f := &tx.Files[i]
+		if f.Path != fPath {
+			continue
+		}
+		if f.At {
+			base, err := readOptional(fPath)
+			if err != nil {
+				return err
+			}
+			*f = FileWrite{Path: fPath, Data: f.Content(base)}
+		}
+		if f.Remove {
+			f.Remove = false
+			f.Data = nil
+		}
+		f.Data = append(f.Data, data...)
+		return nil
+	}
+	var offset int64
+	info, err := os.Stat(fPath)
+	switch {
+	case err == nil:
+		offset = info.Size()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	tx.Files = append(tx.Files, FileWrite{Path: fPath, Data: data, Append: true, Offset: offset})
+	return nil
+}
+
+// WriteAt stages writing data at offset of fPath, keeping what the file holds before and
+// behind it. A path the transaction already writes gets its whole new content instead.
+func (tx *Tx) WriteAt(fPath string, offset int64, data []byte) error {
+	w := FileWrite{Path: fPath, Data: data, At: true, Offset: offset}
+	for i := range tx.Files {
+		f := tx.Files[i]
+		if f.Path != fPath {
+			continue
+		}
+		base, err := readOptional(fPath)
+		if err != nil {
+			return err
+		}
+		tx.Files[i] = FileWrite{Path: fPath, Data: w.Content(f.Content(base))}
+		return nil
+	}
+	tx.Files = append(tx.Files, w)
+	return nil
+}
+
+// readOptional reads fPath, a missing file is empty.
+func readOptional(fPath string) ([]byte, error) {
+	data, err := os.ReadFile(fPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Pending returns the staged content of fPath, so later steps of the same transaction read their own writes.
+func (tx *Tx) Pending(fPath string) (FileWrite, bool) {
+	for _, f := range tx.Files {
+		if f.Path == fPath {
+			return f, true
+		}
+	}
+	return FileWrite{}, false
+}
+
+// Snapshot returns the current content of every file tx replaces or removes, as the
+// writes that would put it back. Appends are left out.
+func (tx *Tx) Snapshot() ([]FileWrite, error) {
+	res := make([]FileWrite, 0, len(tx.Files))
+	for _, f := range tx.Files {
+		if f.Append {
+			continue
+		}
+		data, err := os.ReadFile(f.Path)
+		switch {
+		case err == nil:
+			res = append(res, FileWrite{Path: f.Path, Data: data})
+		case errors.Is(err, os.ErrNotExist):
+			res = append(res, FileWrite{Path: f.Path, Remove: true})
+		default:
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (tx *Tx) EncodeTasks(fPath string, src map[int64]*types.Task) error {
+	data, err := marshalJSON(src)
+	if err != nil {
+		return err
+	}
+	tx.Write(fPath, data)
+	return nil
+}
+
+func (tx *Tx) EncodeIndex(fPath string, src map[int][]int64) error {
+	data, err := marshalJSON(src)
+	if err != nil {
+		return err
+	}
+	tx.Write(fPath, data)
+	return nil
+}
+
+func (tx *Tx) WriteLastID(id int64, fPath string) error {
+	data, err := marshalJSON(map[string]int64{label: id})
+	if err != nil {
+		return err
+	}
+	tx.Write(fPath, data)
+	return nil
+}
+
+// ErrPendingJournal is returned by Commit while the journal still holds an earlier
+// transaction, which Recover has to finish first.
+var ErrPendingJournal = errors.New("an earlier change was not finished")
+
+// Commit makes all staged writes durable. See Tx for the protocol. A journal left by an
+// earlier transaction is not overwritten, that transaction would be lost half applied.
+func (tx *Tx) Commit() error {
+	if len(tx.Files) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(tx.journalPath); err == nil {
+		return fmt.Errorf("%w: %s", ErrPendingJournal, tx.journalPath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(tx.journalPath, data); err != nil {
+		return err
+	}
+	return tx.apply()
+}
+
+func (tx *Tx) apply() error {
+	for _, f := range tx.Files {
+		if f.Remove {
+			if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+			return err
+		}
+		if f.Append || f.At {
+			if err := writeAt(f.Path, f.Data, f.Offset, f.Append); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := WriteFileAtomic(f.Path, f.Data); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(tx.journalPath); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(tx.journalPath))
+}
+
+// writeAt writes data at offset of fPath. With truncate everything behind it is cut off.
+func writeAt(fPath string, data []byte, offset int64, truncate bool) error {
+	f, err := os.OpenFile(fPath, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if truncate {
+		if err := f.Truncate(offset); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.WriteAt(data, offset); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recover finishes a transaction interrupted by a crash. A committed journal is rolled
+// forward. Nothing has to be rolled back: without a journal no target file was touched,
+// so only temp files are left and those are removed.
+func Recover(journalPath string) (bool, error) {
+	removeTemps(filepath.Dir(journalPath), filepath.Base(journalPath))
+	data, err := os.ReadFile(journalPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	tx := &Tx{journalPath: journalPath}
+	if err := json.Unmarshal(data, tx); err != nil {
+		return false, err
+	}
+	for _, f := range tx.Files {
+		removeTemps(filepath.Dir(f.Path), filepath.Base(f.Path))
+	}
+	if err := tx.apply(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeTemps deletes leftovers of WriteFileAtomic for the file name in dir.
+func removeTemps(dir, name string) {
+	matches, _ := filepath.Glob(filepath.Join(dir, "."+name+".tmp-*"))
+	for _, m := range matches {
+		os.Remove(m)
+	}
+}
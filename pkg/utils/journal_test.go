@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"taskTracker/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxCommit(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal.json")
+	tPath := filepath.Join(dir, "tasks", "2026", "5.json")
+	idPath := filepath.Join(dir, "lastID.json")
+
+	tx := Begin(journal, "create")
+	require.NoError(t, tx.EncodeTasks(tPath, map[int64]*types.Task{1: {ID: 1, Description: "first"}}))
+	require.NoError(t, tx.WriteLastID(1, idPath))
+	require.NoError(t, tx.Commit())
+
+	t.Run("files written", func(t *testing.T) {
+		m := make(map[int64]*types.Task)
+		require.NoError(t, DecodeTasks(tPath, m))
+		assert.Equal(t, "first", m[1].Description)
+		id, err := ReadLastID(idPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), id)
+	})
+
+	t.Run("journal removed", func(t *testing.T) {
+		_, err := os.Stat(journal)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("an unfinished journal is not overwritten", func(t *testing.T) {
+		first := Begin(journal, "create")
+		first.Write(idPath, []byte("a"))
+		data, err := json.Marshal(first)
+		require.NoError(t, err)
+		require.NoError(t, WriteFileAtomic(journal, data))
+
+		tx := Begin(journal, "update")
+		tx.Write(idPath, []byte("b"))
+		require.ErrorIs(t, tx.Commit(), ErrPendingJournal)
+		ok, err := Recover(journal)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		require.NoError(t, tx.Commit())
+	})
+
+	t.Run("write at keeps the rest of the file", func(t *testing.T) {
+		fPath := filepath.Join(dir, "ids.txt")
+		require.NoError(t, os.WriteFile(fPath, []byte("aa\nbb\ncc\n"), 0644))
+		tx := Begin(journal, "update")
+		require.NoError(t, tx.WriteAt(fPath, 3, []byte("BB\n")))
+		f, ok := tx.Pending(fPath)
+		require.True(t, ok)
+		assert.Equal(t, "aa\nBB\ncc\n", string(f.Content([]byte("aa\nbb\ncc\n"))))
+		require.NoError(t, tx.WriteAt(fPath, 9, []byte("dd\n")))
+		require.NoError(t, tx.Commit())
+		data, err := os.ReadFile(fPath)
+		require.NoError(t, err)
+		assert.Equal(t, "aa\nBB\ncc\ndd\n", string(data))
+	})
+
+	t.Run("pending reads own writes", func(t *testing.T) {
+		tx := Begin(journal, "delete")
+		tx.Write(idPath, []byte("a"))
+		tx.Write(idPath, []byte("b"))
+		f, ok := tx.Pending(idPath)
+		require.True(t, ok)
+		assert.Equal(t, "b", string(f.Data))
+		assert.Len(t, tx.Files, 1)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	t.Run("nothing to recover", func(t *testing.T) {
+		ok, err := Recover(filepath.Join(t.TempDir(), "journal.json"))
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("committed journal is rolled forward", func(t *testing.T) {
+		dir := t.TempDir()
+		journal := filepath.Join(dir, "journal.json")
+		tPath := filepath.Join(dir, "5.json")
+		idPath := filepath.Join(dir, "lastID.json")
+		gone := filepath.Join(dir, "gone.json")
+		require.NoError(t, os.WriteFile(tPath, []byte("{}"), 0644))
+		require.NoError(t, os.WriteFile(gone, []byte("{}"), 0644))
+
+		// crash right after the commit marker: journal is there, targets are untouched
+		tx := Begin(journal, "create")
+		require.NoError(t, tx.EncodeTasks(tPath, map[int64]*types.Task{7: {ID: 7}}))
+		require.NoError(t, tx.WriteLastID(7, idPath))
+		tx.Remove(gone)
+		data, err := json.Marshal(tx)
+		require.NoError(t, err)
+		require.NoError(t, WriteFileAtomic(journal, data))
+
+		ok, err := Recover(journal)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		m := make(map[int64]*types.Task)
+		require.NoError(t, DecodeTasks(tPath, m))
+		assert.Contains(t, m, int64(7))
+		id, err := ReadLastID(idPath)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), id)
+		_, err = os.Stat(gone)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(journal)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("append is not repeated by a second roll forward", func(t *testing.T) {
+		dir := t.TempDir()
+		journal := filepath.Join(dir, "journal.json")
+		logPath := filepath.Join(dir, "logs", "10.log")
+		require.NoError(t, os.MkdirAll(filepath.Dir(logPath), 0755))
+		require.NoError(t, os.WriteFile(logPath, []byte("a\n"), 0644))
+
+		tx := Begin(journal, "update")
+		require.NoError(t, tx.Append(logPath, []byte("b\n")))
+		require.NoError(t, tx.Append(logPath, []byte("c\n")))
+		data, err := json.Marshal(tx)
+		require.NoError(t, err)
+		require.NoError(t, WriteFileAtomic(journal, data))
+		// crash after the append landed but before the journal was removed
+		require.NoError(t, tx.apply())
+		require.NoError(t, WriteFileAtomic(journal, data))
+
+		ok, err := Recover(journal)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		res, err := os.ReadFile(logPath)
+		require.NoError(t, err)
+		assert.Equal(t, "a\nb\nc\n", string(res))
+	})
+
+	t.Run("uncommitted journal is discarded", func(t *testing.T) {
+		dir := t.TempDir()
+		journal := filepath.Join(dir, "journal.json")
+		tmp := filepath.Join(dir, ".journal.json.tmp-123")
+		require.NoError(t, os.WriteFile(tmp, []byte(`{"op":"create","fi`), 0644))
+
+		ok, err := Recover(journal)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		_, err = os.Stat(tmp)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
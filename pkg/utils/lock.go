@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrStorageBusy is returned when a lock could not be taken before the timeout ran out.
+var ErrStorageBusy = errors.New("storage busy: another taskTracker process is using it")
+
+// errWouldBlock is returned by tryLock when the lock is held by someone else.
+var errWouldBlock = errors.New("lock would block")
+
+const lockRetry = 10 * time.Millisecond
+
+// Lock is an advisory lock on a file shared by all taskTracker processes.
+// Readers take it shared, writers exclusive.
+type Lock struct {
+	f *os.File
+}
+
+// LockShared waits up to timeout for a shared lock on fPath, creating the file if needed.
+func LockShared(fPath string, timeout time.Duration) (*Lock, error) {
+	return lock(fPath, false, timeout)
+}
+
+// LockExclusive waits up to timeout for an exclusive lock on fPath, creating the file if needed.
+func LockExclusive(fPath string, timeout time.Duration) (*Lock, error) {
+	return lock(fPath, true, timeout)
+}
+
+func lock(fPath string, exclusive bool, timeout time.Duration) (*Lock, error) {
+	f, err := os.OpenFile(fPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(f, exclusive)
+		if err == nil {
+			return &Lock{f: f}, nil
+		}
+		if !errors.Is(err, errWouldBlock) {
+			f.Close()
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%w (waited %v for %s)", ErrStorageBusy, timeout, fPath)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+func (l *Lock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cErr := l.f.Close(); err == nil {
+		err = cErr
+	}
+	l.f = nil
+	return err
+}
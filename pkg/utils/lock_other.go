@@ -0,0 +1,22 @@
+//go:build !unix && !windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+// The standard library has no portable advisory locking outside unix and windows.
+// Going on without a lock could let two processes overwrite each other's changes,
+// so taking one fails instead.
+var errNoLocking = errors.New("file locking is not supported on " + runtime.GOOS)
+
+func tryLock(f *os.File, exclusive bool) error {
+	return errNoLocking
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	fPath := filepath.Join(t.TempDir(), "lock")
+
+	t.Run("readers share the lock", func(t *testing.T) {
+		a, err := LockShared(fPath, time.Second)
+		require.NoError(t, err)
+		b, err := LockShared(fPath, time.Second)
+		require.NoError(t, err)
+		assert.NoError(t, a.Unlock())
+		assert.NoError(t, b.Unlock())
+	})
+
+	t.Run("writer waits for readers -> storage busy", func(t *testing.T) {
+		r, err := LockShared(fPath, time.Second)
+		require.NoError(t, err)
+		_, err = LockExclusive(fPath, 50*time.Millisecond)
+		require.ErrorIs(t, err, ErrStorageBusy)
+		require.NoError(t, r.Unlock())
+
+		w, err := LockExclusive(fPath, time.Second)
+		require.NoError(t, err)
+		_, err = LockShared(fPath, 50*time.Millisecond)
+		require.ErrorIs(t, err, ErrStorageBusy)
+		require.NoError(t, w.Unlock())
+	})
+
+	t.Run("lock is taken once released", func(t *testing.T) {
+		w, err := LockExclusive(fPath, time.Second)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			w.Unlock()
+		}()
+		w2, err := LockExclusive(fPath, 2*time.Second)
+		require.NoError(t, err)
+		assert.NoError(t, w2.Unlock())
+	})
+
+	t.Run("unlock twice is harmless", func(t *testing.T) {
+		w, err := LockExclusive(fPath, time.Second)
+		require.NoError(t, err)
+		assert.NoError(t, w.Unlock())
+		assert.NoError(t, w.Unlock())
+	})
+}
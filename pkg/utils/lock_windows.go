@@ -0,0 +1,50 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// The syscall package does not wrap LockFileEx, so it is called from kernel32 directly.
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+	allBytes                = uintptr(^uint32(0)) // low and high half of the length locked
+)
+
+// tryLock locks the whole file, like flock does on unix.
+func tryLock(f *os.File, exclusive bool) error {
+	flags := uint32(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	ol := new(syscall.Overlapped)
+	r, _, err := procLockFileEx.Call(f.Fd(), uintptr(flags), 0, allBytes, allBytes, uintptr(unsafe.Pointer(ol)))
+	if r != 0 {
+		return nil
+	}
+	if errors.Is(err, errorLockViolation) {
+		return errWouldBlock
+	}
+	return err
+}
+
+func unlock(f *os.File) error {
+	ol := new(syscall.Overlapped)
+	r, _, err := procUnlockFileEx.Call(f.Fd(), 0, allBytes, allBytes, uintptr(unsafe.Pointer(ol)))
+	if r != 0 {
+		return nil
+	}
+	return err
+}
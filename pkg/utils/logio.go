@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"taskTracker/pkg/types"
+)
+
+// DecodeEvents reads an audit log file, one json event per line, and returns the
+// events of the task with the given id in the order they were written.
+func DecodeEvents(fPath string, id int64) ([]types.Event, error) {
+	rFile, err := os.Open(fPath)
+	if err != nil {
+		return nil, err
+	}
+	defer rFile.Close()
+
+	res := make([]types.Event, 0)
+	sc := bufio.NewScanner(rFile)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		ev := types.Event{}
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			return nil, err
+		}
+		if ev.TaskID == id {
+			res = append(res, ev)
+		}
+	}
+	return res, sc.Err()
+}
+
+// EncodeEvent returns ev as one line of an audit log file.
+func EncodeEvent(ev types.Event) ([]byte, error) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// CurrentUser names who is running the program for the audit log.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
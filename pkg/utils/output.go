@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+	"unicode/utf8"
+)
+
+// Format is an output format of the read commands.
+type Format string
+
+const (
+	FormatPlain  Format = "plain"
+	FormatTable  Format = "table"
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatCSV    Format = "csv"
+)
+
+var Formats = []Format{FormatPlain, FormatTable, FormatJSON, FormatNDJSON, FormatCSV}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q", s)
+}
+
+// column is one field of a task in csv and table output. Names follow the json tags of types.Task.
+type column struct {
+	name  string
+	value func(t *types.Task) string
+	table func(t *types.Task) string // shorter form for the table, value is used when nil
+	// noTable leaves the column out of the table, where it would only repeat another one
+	// or is empty for most tasks
+	noTable bool
+}
+
+const tableTime = "2006-01-02 15:04"
+
+var columns = []column{
+	{name: "id", value: func(t *types.Task) string { return strconv.FormatInt(t.ID, 10) }},
+	{name: "status", value: func(t *types.Task) string { return string(t.Status) }},
+	{name: "done", value: func(t *types.Task) string { return strconv.FormatBool(t.Done()) }, noTable: true},
+	{
+		name:  "created_at",
+		value: func(t *types.Task) string { return t.CreatedAt.Format(time.RFC3339) },
+		table: func(t *types.Task) string { return t.CreatedAt.Format(tableTime) },
+	},
+	{
+		name:  "updated_at",
+		value: func(t *types.Task) string { return t.UpdateAt.Format(time.RFC3339) },
+		table: func(t *types.Task) string { return t.UpdateAt.Format(tableTime) },
+	},
+	{name: "priority", value: func(t *types.Task) string { return string(t.Priority.OrDefault()) }},
+	{
+		name:  "due_at",
+		value: func(t *types.Task) string { return formatOptional(t.DueAt, time.RFC3339) },
+		table: func(t *types.Task) string { return formatOptional(t.DueAt, types.DateLayout) },
+	},
+	{name: "done_at", value: func(t *types.Task) string { return formatOptional(t.DoneAt, time.RFC3339) }, noTable: true},
+	{name: "project", value: func(t *types.Task) string { return t.Project }, noTable: true},
+	{name: "created_by", value: func(t *types.Task) string { return t.CreatedBy }, noTable: true},
+	{name: "assignee", value: func(t *types.Task) string { return t.Assignee }, noTable: true},
+	{name: "estimate", value: func(t *types.Task) string { return types.FormatEstimate(t.Estimate) }, noTable: true},
+	{name: "tags", value: func(t *types.Task) string { return strings.Join(t.Tags, ",") }},
+	{name: "parent_id", value: func(t *types.Task) string { return formatID(t.ParentID) }, noTable: true},
+	{name: "depends_on", value: func(t *types.Task) string { return formatIDs(t.DependsOn) }, noTable: true},
+	{name: "deleted_at", value: func(t *types.Task) string { return formatOptional(t.DeletedAt, time.RFC3339) }, noTable: true},
+	// description stays last so the table can cut it at the terminal width
+	{
+		name:  "description",
+		value: func(t *types.Task) string { return t.Description },
+		table: func(t *types.Task) string { return strings.Join(strings.Fields(t.Description), " ") },
+	},
+}
+
+// formatID formats id, leaving 0 empty.
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func formatIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatOptional formats t, leaving unset times empty.
+func formatOptional(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// WriteTasks writes tasks to w in format f. width is only used by FormatTable.
+func WriteTasks(w io.Writer, f Format, tasks []*types.Task, width int) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tasks)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, t := range tasks {
+			if err := enc.Encode(t); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		return writeCSV(w, tasks)
+	case FormatTable:
+		return writeTable(w, tasks, width)
+	default:
+		for _, t := range tasks {
+			ShowTask(w, *t)
+		}
+		return nil
+	}
+}
+
+// WriteTree is WriteTasks, except that the plain format shows subtasks indented below
+// their parent and the progress of every task with subtasks. A subtask whose parent
+// is not in tasks is shown at the top level.
+func WriteTree(w io.Writer, f Format, tasks []*types.Task, progress map[int64]types.Progress, width int) error {
+	if f != FormatPlain {
+		return WriteTasks(w, f, tasks, width)
+	}
+	listed := make(map[int64]bool, len(tasks))
+	for _, t := range tasks {
+		listed[t.ID] = true
+	}
+	children := make(map[int64][]*types.Task)
+	roots := make([]*types.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if t.ParentID != 0 && listed[t.ParentID] && t.ParentID != t.ID {
+			children[t.ParentID] = append(children[t.ParentID], t)
+		} else {
+			roots = append(roots, t)
+		}
+	}
+	seen := make(map[int64]bool, len(tasks))
+	var show func(t *types.Task, depth int) error
+	show = func(t *types.Task, depth int) error {
+		if seen[t.ID] {
+			return nil
+		}
+		seen[t.ID] = true
+		line := *t
+		if depth > 0 {
+			line.ParentID = 0 // the indentation already shows it
+		}
+		if _, err := fmt.Fprintln(w, strings.Repeat("    ", depth)+taskLine(line, progress[t.ID])); err != nil {
+			return err
+		}
+		for _, c := range children[t.ID] {
+			if err := show(c, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// tasks left over after the roots have parents that form a cycle, show them anyway
+	for _, t := range append(roots, tasks...) {
+		if err := show(t, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTask writes a single task. Unlike WriteTasks the json format is an object, not an array.
+func WriteTask(w io.Writer, f Format, t *types.Task, width int) error {
+	if f == FormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t)
+	}
+	return WriteTasks(w, f, []*types.Task{t}, width)
+}
+
+func writeCSV(w io.Writer, tasks []*types.Task) error {
+	cw := csv.NewWriter(w)
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		row[i] = c.name
+	}
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		for i, c := range columns {
+			row[i] = c.value(t)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTable(w io.Writer, tasks []*types.Task, width int) error {
+	columns := tableColumns()
+	rows := make([][]string, 0, len(tasks)+1)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c.name)
+	}
+	rows = append(rows, header)
+	for _, t := range tasks {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			if c.table != nil {
+				row[i] = c.table(t)
+			} else {
+				row[i] = c.value(t)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return writeAligned(w, rows, width)
+}
+
+// writeAligned pads every column of rows to the same width. The last column gets what
+// is left of width and is cut to fit.
+func writeAligned(w io.Writer, rows [][]string, width int) error {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	// whatever is left of the line after the other columns goes to the last one
+	used := 0
+	for _, cw := range widths[:len(widths)-1] {
+		used += cw + 2
+	}
+	last := max(width-used, utf8.RuneCountInString(rows[0][len(rows[0])-1]))
+
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row[:len(row)-1] {
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+		}
+		b.WriteString(truncate(row[len(row)-1], last))
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableColumns() []column {
+	res := make([]column, 0, len(columns))
+	for _, c := range columns {
+		if !c.noTable {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// truncate cuts s to n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if n < 1 {
+		return ""
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// TerminalWidth returns $COLUMNS, or 80 when it is not set.
+func TerminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
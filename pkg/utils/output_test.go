@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"taskTracker/pkg/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleTasks() []*types.Task {
+	at := time.Date(2026, time.May, 4, 9, 30, 0, 0, time.UTC)
+	return []*types.Task{
+		{ID: 1, Description: "short", Status: types.StatusTodo, CreatedAt: at, UpdateAt: at},
+		{
+			ID: 12, Description: "a rather long description, with a comma", Status: types.StatusDone, CreatedAt: at, UpdateAt: at.Add(time.Hour),
+			Priority: types.PriorityUrgent, DueAt: at.AddDate(0, 0, 7), Tags: []string{"ops", "q2"},
+			DoneAt: at.Add(time.Hour), Estimate: 2.5,
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		res, err := ParseFormat(string(f))
+		require.NoError(t, err)
+		assert.Equal(t, f, res)
+	}
+	_, err := ParseFormat("xml")
+	require.Error(t, err)
+}
+
+func TestWriteTasks(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTasks(buf, FormatJSON, sampleTasks(), 80))
+		var res []map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+		require.Len(t, res, 2)
+		assert.Equal(t, "short", res[0]["description"])
+		assert.Equal(t, "2026-05-04T09:30:00Z", res[0]["created_at"])
+	})
+
+	t.Run("json of no tasks is an empty array", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTasks(buf, FormatJSON, []*types.Task{}, 80))
+		assert.Equal(t, "[]\n", buf.String())
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTasks(buf, FormatNDJSON, sampleTasks(), 80))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		res := types.Task{}
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &res))
+		assert.Equal(t, int64(12), res.ID)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTasks(buf, FormatCSV, sampleTasks(), 80))
+		rows, err := csv.NewReader(buf).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, []string{"id", "status", "done", "created_at", "updated_at", "priority", "due_at", "done_at", "project", "created_by", "assignee", "estimate", "tags", "parent_id", "depends_on", "deleted_at", "description"}, rows[0])
+		assert.Equal(t, []string{"1", "todo", "false", "2026-05-04T09:30:00Z", "2026-05-04T09:30:00Z", "normal", "", "", "", "", "", "", "", "", "", "", "short"}, rows[1])
+		assert.Equal(t, []string{
+			"12", "done", "true", "2026-05-04T09:30:00Z", "2026-05-04T10:30:00Z", "urgent", "2026-05-11T09:30:00Z", "2026-05-04T10:30:00Z", "", "", "", "2.5", "ops,q2", "", "", "",
+			"a rather long description, with a comma",
+		}, rows[2])
+	})
+
+	t.Run("table aligns and truncates", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTasks(buf, FormatTable, sampleTasks(), 90))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasPrefix(lines[0], "ID  STATUS  CREATED_AT"))
+		assert.Equal(t, strings.Index(lines[0], "DESCRIPTION"), strings.Index(lines[1], "short"))
+		assert.LessOrEqual(t, len([]rune(lines[2])), 90)
+		assert.True(t, strings.HasSuffix(lines[2], "…"))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTasks(buf, FormatPlain, sampleTasks(), 80))
+		assert.True(t, strings.HasPrefix(buf.String(), "1. short / status: todo --- Created"))
+		assert.Contains(t, buf.String(), "12. a rather long description, with a comma / status: done / priority: urgent / due: 11 May 26 09:30 UTC / tags: ops,q2")
+	})
+}
+
+func TestWriteTree(t *testing.T) {
+	at := time.Date(2026, 5, 4, 9, 30, 0, 0, time.UTC)
+	tasks := []*types.Task{
+		{ID: 1, Description: "release", Status: types.StatusTodo, CreatedAt: at, UpdateAt: at},
+		{ID: 2, Description: "changelog", Status: types.StatusDone, ParentID: 1, CreatedAt: at, UpdateAt: at},
+		{ID: 3, Description: "orphan", Status: types.StatusTodo, ParentID: 9, CreatedAt: at, UpdateAt: at},
+	}
+	progress := map[int64]types.Progress{1: {Done: 1, Total: 2}}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteTree(buf, FormatPlain, tasks, progress, 80))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "1. release / status: todo / 1/2 subtasks done ---"))
+	assert.True(t, strings.HasPrefix(lines[1], "    2. changelog / status: done ---"))
+	assert.True(t, strings.HasPrefix(lines[2], "3. orphan / status: todo / parent: 9 ---"))
+
+	t.Run("cycles are still shown", func(t *testing.T) {
+		cyclic := []*types.Task{{ID: 1, ParentID: 2}, {ID: 2, ParentID: 1}}
+		buf := &bytes.Buffer{}
+		require.NoError(t, WriteTree(buf, FormatPlain, cyclic, nil, 80))
+		assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+	})
+}
+
+func TestWriteTask(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteTask(buf, FormatJSON, sampleTasks()[0], 80))
+	res := types.Task{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &res))
+	assert.Equal(t, int64(1), res.ID)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, "ab…", truncate("abcd", 3))
+	assert.Equal(t, "", truncate("abcd", 0))
+}
+
+func TestWriteStats(t *testing.T) {
+	st := types.Stats{
+		From: "2026-10-12", To: "2026-10-13", Created: 4, Completed: 2, CompletionRate: 0.25, LeadTimeSeconds: 30 * 3600,
+		Burndown: []types.BurndownDay{
+			{Day: "2026-10-12", Created: 3, Open: 4, Estimate: 8},
+			{Day: "2026-10-13", Created: 1, Completed: 2, Open: 3, Estimate: 2.5},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteStats(buf, FormatPlain, st, 50))
+	assert.Equal(t, "2026-10-12 to 2026-10-13\n"+
+		"Created: 4, completed: 2, completion rate: 25%\n"+
+		"Average lead time: 1d06h\n"+
+		"Burndown, estimate left (open tasks):\n"+
+		"2026-10-12 Mon "+strings.Repeat("#", 20)+" 8 (4)\n"+
+		"2026-10-13 Tue "+strings.Repeat("#", 6)+strings.Repeat(" ", 14)+" 2.5 (3)\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteStats(buf, FormatCSV, st, 80))
+	assert.Equal(t, "day,created,completed,open,estimate\n2026-10-12,3,0,4,8\n2026-10-13,1,2,3,2.5\n", buf.String())
+}
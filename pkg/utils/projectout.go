@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// ProjectSummary is a project with the count of its tasks, as written by WriteProjects.
+type ProjectSummary struct {
+	*types.Project
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// WriteProjects writes projects to w in format f. width is only used by FormatTable.
+func WriteProjects(w io.Writer, f Format, projects []ProjectSummary, width int) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(projects)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, p := range projects {
+			if err := enc.Encode(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"name", "done", "total", "created_at", "description"})
+		for _, row := range projectRows(projects, time.RFC3339) {
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTable:
+		rows := [][]string{{"NAME", "DONE", "TOTAL", "CREATED_AT", "DESCRIPTION"}}
+		return writeAligned(w, append(rows, projectRows(projects, tableTime)...), width)
+	default:
+		for _, p := range projects {
+			desc := ""
+			if p.Description != "" {
+				desc = " / " + p.Description
+			}
+			fmt.Fprintf(w, "%v: %d/%d tasks done%v\n", p.Name, p.Done, p.Total, desc)
+		}
+		return nil
+	}
+}
+
+func projectRows(projects []ProjectSummary, layout string) [][]string {
+	rows := make([][]string, 0, len(projects))
+	for _, p := range projects {
+		rows = append(rows, []string{
+			p.Name, strconv.Itoa(p.Done), strconv.Itoa(p.Total), p.CreatedAt.Format(layout), p.Description,
+		})
+	}
+	return rows
+}
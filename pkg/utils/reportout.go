@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// FormatSpent writes a duration in hours and minutes, 2h05m.
+func FormatSpent(d time.Duration) string {
+	m := int64(d.Round(time.Minute) / time.Minute)
+	return fmt.Sprintf("%dh%02dm", m/60, m%60)
+}
+
+// hours writes a duration as decimal hours for spreadsheets.
+func hours(d time.Duration) string {
+	return strconv.FormatFloat(d.Hours(), 'f', 2, 64)
+}
+
+// WriteTimeReport writes the entries of a time report to w in format f. The plain format
+// groups them by day with the total of every day and of the whole report. width is
+// only used by FormatTable.
+func WriteTimeReport(w io.Writer, f Format, entries []types.TimeEntry, width int) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"day", "task_id", "project", "hours", "description"})
+		for _, row := range reportRows(entries) {
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTable:
+		rows := [][]string{{"DAY", "TASK_ID", "PROJECT", "HOURS", "DESCRIPTION"}}
+		return writeAligned(w, append(rows, reportRows(entries)...), width)
+	default:
+		var total time.Duration
+		for i := 0; i < len(entries); {
+			day := entries[i].Day
+			j := i
+			var sum time.Duration
+			for ; j < len(entries) && entries[j].Day == day; j++ {
+				sum += entries[j].Spent()
+			}
+			weekday := ""
+			if d, err := types.ParseDate(day); err == nil {
+				weekday = " " + d.Weekday().String()[:3]
+			}
+			fmt.Fprintf(w, "%v%v: %v\n", day, weekday, FormatSpent(sum))
+			for _, e := range entries[i:j] {
+				fmt.Fprintf(w, "    %v. %v: %v\n", e.TaskID, e.Description, FormatSpent(e.Spent()))
+			}
+			total += sum
+			i = j
+		}
+		fmt.Fprintf(w, "Total: %v\n", FormatSpent(total))
+		return nil
+	}
+}
+
+func reportRows(entries []types.TimeEntry) [][]string {
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, []string{e.Day, strconv.FormatInt(e.TaskID, 10), e.Project, hours(e.Spent()), e.Description})
+	}
+	return rows
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// FormatLeadTime writes a duration in days and hours, 2d05h, or hours and minutes
+// when it is shorter than a day.
+func FormatLeadTime(d time.Duration) string {
+	if d < 24*time.Hour {
+		return FormatSpent(d)
+	}
+	h := int64(d.Round(time.Hour) / time.Hour)
+	return fmt.Sprintf("%dd%02dh", h/24, h%24)
+}
+
+// WriteStats writes st to w in format f. JSON holds all of it, NDJSON, CSV and the
+// table the burndown days only. The plain format draws the burndown as bars of the
+// estimate left, or of the open tasks when no task has an estimate; width sets how
+// long the bars can get.
+func WriteStats(w io.Writer, f Format, st types.Stats, width int) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, bd := range st.Burndown {
+			if err := enc.Encode(bd); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"day", "created", "completed", "open", "estimate"})
+		for _, row := range burndownRows(st.Burndown) {
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTable:
+		rows := [][]string{{"DAY", "CREATED", "COMPLETED", "OPEN", "ESTIMATE"}}
+		return writeAligned(w, append(rows, burndownRows(st.Burndown)...), width)
+	default:
+		fmt.Fprintf(w, "%v to %v\n", st.From, st.To)
+		fmt.Fprintf(w, "Created: %v, completed: %v, completion rate: %.0f%%\n", st.Created, st.Completed, st.CompletionRate*100)
+		lead := "-"
+		if st.Completed > 0 {
+			lead = FormatLeadTime(st.LeadTime())
+		}
+		fmt.Fprintf(w, "Average lead time: %v\n", lead)
+		writeBurndown(w, st.Burndown, width)
+		return nil
+	}
+}
+
+func writeBurndown(w io.Writer, days []types.BurndownDay, width int) {
+	if len(days) == 0 {
+		return
+	}
+	byEstimate := false
+	for _, bd := range days {
+		byEstimate = byEstimate || bd.Estimate > 0
+	}
+	value := func(bd types.BurndownDay) float64 {
+		if byEstimate {
+			return bd.Estimate
+		}
+		return float64(bd.Open)
+	}
+	top := 0.0
+	for _, bd := range days {
+		top = max(top, value(bd))
+	}
+	// the day and the numbers after the bar take about 30 columns
+	bar := min(max(width-30, 10), 50)
+
+	if byEstimate {
+		fmt.Fprintln(w, "Burndown, estimate left (open tasks):")
+	} else {
+		fmt.Fprintln(w, "Burndown, open tasks:")
+	}
+	for _, bd := range days {
+		n := 0
+		if top > 0 {
+			n = int(math.Round(value(bd) / top * float64(bar)))
+		}
+		if n == 0 && value(bd) > 0 {
+			n = 1
+		}
+		label := strconv.Itoa(bd.Open)
+		if byEstimate {
+			label = fmt.Sprintf("%v (%v)", strconv.FormatFloat(bd.Estimate, 'f', -1, 64), bd.Open)
+		}
+		weekday := ""
+		if d, err := types.ParseDate(bd.Day); err == nil {
+			weekday = " " + d.Weekday().String()[:3]
+		}
+		fmt.Fprintf(w, "%v%v %v %v\n", bd.Day, weekday, strings.Repeat("#", n)+strings.Repeat(" ", bar-n), label)
+	}
+}
+
+func burndownRows(days []types.BurndownDay) [][]string {
+	rows := make([][]string, 0, len(days))
+	for _, bd := range days {
+		rows = append(rows, []string{
+			bd.Day, strconv.Itoa(bd.Created), strconv.Itoa(bd.Completed), strconv.Itoa(bd.Open),
+			strconv.FormatFloat(bd.Estimate, 'f', -1, 64),
+		})
+	}
+	return rows
+}
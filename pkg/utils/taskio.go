@@ -8,12 +8,13 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"taskTracker/pkg/types"
 	"time"
 )
 
 var (
-	label  = "lastID"
+	label = "lastID"
 )
 
 func SetStorage(taskPath, indexPath, logsPath string) error {
@@ -30,7 +31,7 @@ func SetStorage(taskPath, indexPath, logsPath string) error {
 }
 
 // GetTargetPath returns path to the file to write
-func GetTargetPath(storagePath string) (string) {
+func GetTargetPath(storagePath string) string {
 	t := time.Now().Local()
 	fName := fmt.Sprintf("%d.json", t.Month())
 	fPath := filepath.Join(storagePath, strconv.Itoa(t.Year()), fName)
@@ -80,32 +81,23 @@ func DecodeTasks(fPath string, dst map[int64]*types.Task) error {
 	return nil
 }
 
-// EncodeFile saves data from src to json file.
+// EncodeFile saves data from src to json file. The file is replaced atomically.
 func EncodeTasks(fPath string, src map[int64]*types.Task) error {
-	wFile, err := os.OpenFile(fPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	data, err := marshalJSON(&src)
 	if err != nil {
 		return err
 	}
-	defer wFile.Close()
-	if err := json.NewEncoder(wFile).Encode(&src); err != nil {
-		return err
-	}
-	return nil
+	return WriteFileAtomic(fPath, data)
 }
 
 func WriteLastID(id int64, fPath string) error {
 	m := make(map[string]int64)
 	m[label] = id
-	file, err := os.OpenFile(fPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	data, err := marshalJSON(&m)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	if err := json.NewEncoder(file).Encode(&m); err != nil {
-		return err
-	}
-	return nil
+	return WriteFileAtomic(fPath, data)
 }
 
 func ReadLastID(fPath string) (int64, error) {
@@ -129,25 +121,51 @@ func ReadLastID(fPath string) (int64, error) {
 
 }
 
-func ShowTask(t types.Task){
-	fmt.Printf("%v. %v / finished: %v --- Created: %v --- Updated: %v\n", t.ID, t.Description, t.Done, t.CreatedAt.Format(time.RFC822), t.UpdateAt.Format(time.RFC822))
+// ShowTask writes one line describing t to w. Priority, due date, tags, the estimate,
+// the parent, the dependencies and the time the task went to the trash are only shown
+// when set.
+func ShowTask(w io.Writer, t types.Task) {
+	fmt.Fprintln(w, taskLine(t, types.Progress{}))
 }
 
-func Help(){
-	println("********************************************************************")
-	println()
-	fmt.Println("-c: flag for creating task. Indicate Description and status of the task via -desc, -done flags")
-	fmt.Println("-u: flag for updating task. Require ID (-id flag )of the target task. Indicate description and status with -desc and -done flags")
-	fmt.Println("-d: flag for deleting task. Require ID of the task (-id flag)")
-	fmt.Println("-g: flag for getting full info about the task with specified ID (-id flag)")
-	fmt.Println("-ld: flag for getting tasks created on specified date -day, -m, -y")
-	fmt.Println("-today: flag that will provide all the tasks created today")
-	fmt.Println("-desc: flag for indicating description of the task")
-	fmt.Println("-done: flag for indicating status of task (true if done else false)")
-	fmt.Println("-id: flag for indicating id of the target task")
-	fmt.Println("-day: flag for indicating day for filter")
-	fmt.Println("-month: flag for indicating month for filter")
-	fmt.Println("-year: flag for indicating year for filter")
-	println()
-	println("********************************************************************")
-}
\ No newline at end of file
+// taskLine describes t in one line, with the subtask roll-up p when it has subtasks.
+func taskLine(t types.Task, p types.Progress) string {
+	extra := ""
+	if t.Project != "" {
+		extra += fmt.Sprintf(" / project: %v", t.Project)
+	}
+	if t.Assignee != "" {
+		extra += fmt.Sprintf(" / assignee: %v", t.Assignee)
+	}
+	if p.Total > 0 {
+		extra += " / " + p.String()
+	}
+	if t.ParentID != 0 {
+		extra += fmt.Sprintf(" / parent: %v", t.ParentID)
+	}
+	if len(t.DependsOn) > 0 {
+		extra += fmt.Sprintf(" / depends on: %v", formatIDs(t.DependsOn))
+	}
+	if t.Priority != "" && t.Priority != types.PriorityNormal {
+		extra += fmt.Sprintf(" / priority: %v", t.Priority)
+	}
+	if !t.DueAt.IsZero() {
+		extra += fmt.Sprintf(" / due: %v", t.DueAt.Format(time.RFC822))
+	}
+	if len(t.Tags) > 0 {
+		extra += fmt.Sprintf(" / tags: %v", strings.Join(t.Tags, ","))
+	}
+	if t.Estimate > 0 {
+		extra += fmt.Sprintf(" / estimate: %v", types.FormatEstimate(t.Estimate))
+	}
+	if spent := t.TimeSpent(); spent > 0 {
+		extra += fmt.Sprintf(" / time: %v", FormatSpent(spent))
+	}
+	if t.Timer != nil {
+		extra += fmt.Sprintf(" / timer: %v since %v", t.Timer.User, t.Timer.Start.Format(time.RFC822))
+	}
+	if t.Deleted() {
+		extra += fmt.Sprintf(" / deleted: %v", t.DeletedAt.Format(time.RFC822))
+	}
+	return fmt.Sprintf("%v. %v / status: %v%v --- Created: %v --- Updated: %v", t.ID, t.Description, t.Status, extra, t.CreatedAt.Format(time.RFC822), t.UpdateAt.Format(time.RFC822))
+}
@@ -125,7 +125,7 @@ func TestDecodeTasks(t *testing.T) {
 	require.NoError(t, err)
 	defer tempFile.Close()
 	tempM := make(map[int64]*types.Task)
-	tempM[10] = &types.Task{ID: 10, Description: "Success", Done: true}
+	tempM[10] = &types.Task{ID: 10, Description: "Success", Status: types.StatusDone}
 	err = json.NewEncoder(tempFile).Encode(tempM)
 	require.NoError(t, err)
 
@@ -156,7 +156,7 @@ func TestEncodeTasks(t *testing.T) {
 
 	t.Run("success", func(t *testing.T) {
 		tempM := make(map[int64]*types.Task)
-		tempM[10] = &types.Task{ID: 10, Description: "Success", Done: true}
+		tempM[10] = &types.Task{ID: 10, Description: "Success", Status: types.StatusDone}
 		err = EncodeTasks(fpath, tempM)
 		require.NoError(t, err)
 
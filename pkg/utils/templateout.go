@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"taskTracker/pkg/types"
+	"time"
+)
+
+// WriteTemplates writes recurring task templates to w in format f. width is only used
+// by FormatTable.
+func WriteTemplates(w io.Writer, f Format, templates []*types.Template, width int) error {
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(templates)
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, tpl := range templates {
+			if err := enc.Encode(tpl); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "schedule", "priority", "project", "tags", "created_at", "last", "description"})
+		for _, row := range templateRows(templates, time.RFC3339) {
+			cw.Write(row)
+		}
+		cw.Flush()
+		return cw.Error()
+	case FormatTable:
+		rows := [][]string{{"ID", "SCHEDULE", "PRIORITY", "PROJECT", "TAGS", "CREATED_AT", "LAST", "DESCRIPTION"}}
+		return writeAligned(w, append(rows, templateRows(templates, tableTime)...), width)
+	default:
+		for _, tpl := range templates {
+			extra := ""
+			if tpl.Project != "" {
+				extra += fmt.Sprintf(" / project: %v", tpl.Project)
+			}
+			if tpl.Priority != "" && tpl.Priority != types.PriorityNormal {
+				extra += fmt.Sprintf(" / priority: %v", tpl.Priority)
+			}
+			if len(tpl.Tags) > 0 {
+				extra += fmt.Sprintf(" / tags: %v", strings.Join(tpl.Tags, ","))
+			}
+			if !tpl.Last.IsZero() {
+				extra += fmt.Sprintf(" / last: %v", tpl.Last.Format(time.RFC822))
+			}
+			fmt.Fprintf(w, "%v. %v / every: %v%v\n", tpl.ID, tpl.Description, tpl.Schedule, extra)
+		}
+		return nil
+	}
+}
+
+func templateRows(templates []*types.Template, layout string) [][]string {
+	rows := make([][]string, 0, len(templates))
+	for _, tpl := range templates {
+		rows = append(rows, []string{
+			strconv.FormatInt(tpl.ID, 10), tpl.Schedule, string(tpl.Priority), tpl.Project, strings.Join(tpl.Tags, ","),
+			tpl.CreatedAt.Format(layout), formatOptional(tpl.Last, layout), tpl.Description,
+		})
+	}
+	return rows
+}